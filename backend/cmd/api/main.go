@@ -12,7 +12,11 @@ import (
 
 	"github.com/jugo/backend/config"
 	"github.com/jugo/backend/internal/api/router"
+	"github.com/jugo/backend/internal/moderation"
 	"github.com/jugo/backend/internal/pkg"
+	"github.com/jugo/backend/internal/prompt"
+	"github.com/jugo/backend/internal/queue"
+	"github.com/jugo/backend/pkg/ai"
 	"github.com/jugo/backend/pkg/logger"
 )
 
@@ -46,9 +50,34 @@ func main() {
 	defer pkg.CloseRedis()
 	zapLogger.Info("Redis connected successfully")
 
+	// 初始化任务队列
+	taskQueue, err := queue.New(&cfg.Queue, pkg.GetRedis())
+	if err != nil {
+		zapLogger.Fatal(fmt.Sprintf("Failed to init task queue: %v", err))
+	}
+	defer taskQueue.Close()
+
+	// 初始化AI提供商
+	providers, err := ai.NewRegistryFromConfig(&cfg.AI)
+	if err != nil {
+		zapLogger.Fatal(fmt.Sprintf("Failed to init AI providers: %v", err))
+	}
+
+	// 加载提示词模板
+	prompts, err := prompt.NewStore(&cfg.AI.Prompts)
+	if err != nil {
+		zapLogger.Fatal(fmt.Sprintf("Failed to load prompt templates: %v", err))
+	}
+
+	// 加载审核词表
+	moderator, err := moderation.NewFilter(&cfg.AI.Moderation)
+	if err != nil {
+		zapLogger.Fatal(fmt.Sprintf("Failed to load moderation word lists: %v", err))
+	}
+
 	// 设置路由
 	db := pkg.GetDB()
-	r := router.Setup(zapLogger, db, cfg)
+	r := router.Setup(zapLogger, db, pkg.GetRedis(), taskQueue, providers, prompts, moderator, cfg)
 
 	// 创建HTTP服务器
 	srv := &http.Server{
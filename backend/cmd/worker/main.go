@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jugo/backend/config"
+	"github.com/jugo/backend/internal/moderation"
+	"github.com/jugo/backend/internal/pkg"
+	"github.com/jugo/backend/internal/prompt"
+	"github.com/jugo/backend/internal/queue"
+	"github.com/jugo/backend/internal/repository"
+	"github.com/jugo/backend/internal/service"
+	"github.com/jugo/backend/pkg/ai"
+	"github.com/jugo/backend/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func main() {
+	concurrency := flag.Int("concurrency", 0, "number of concurrent jobs (overrides queue.concurrency)")
+	flag.Parse()
+
+	// 加载配置
+	cfg, err := config.Load("config/config.yaml")
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if *concurrency > 0 {
+		cfg.Queue.Concurrency = *concurrency
+	}
+
+	// 初始化日志
+	if err := logger.InitLogger(&cfg.Log); err != nil {
+		log.Fatalf("Failed to init logger: %v", err)
+	}
+	defer logger.Sync()
+
+	zapLogger := logger.GetLogger()
+	zapLogger.Info("Starting JUGO AI Worker...")
+
+	if cfg.Queue.Driver == queue.DriverMemory {
+		zapLogger.Fatal("Queue driver 'memory' is consumed inside the API process, worker is not needed")
+	}
+
+	// 初始化数据库
+	if err := pkg.InitDB(&cfg.Database); err != nil {
+		zapLogger.Fatal(fmt.Sprintf("Failed to init database: %v", err))
+	}
+	defer pkg.CloseDB()
+
+	// 初始化Redis
+	if err := pkg.InitRedis(&cfg.Redis); err != nil {
+		zapLogger.Fatal(fmt.Sprintf("Failed to init redis: %v", err))
+	}
+	defer pkg.CloseRedis()
+
+	// 初始化任务队列
+	taskQueue, err := queue.New(&cfg.Queue, pkg.GetRedis())
+	if err != nil {
+		zapLogger.Fatal(fmt.Sprintf("Failed to init task queue: %v", err))
+	}
+	defer taskQueue.Close()
+
+	// 初始化AI提供商
+	providers, err := ai.NewRegistryFromConfig(&cfg.AI)
+	if err != nil {
+		zapLogger.Fatal(fmt.Sprintf("Failed to init AI providers: %v", err))
+	}
+
+	// 加载提示词模板
+	prompts, err := prompt.NewStore(&cfg.AI.Prompts)
+	if err != nil {
+		zapLogger.Fatal(fmt.Sprintf("Failed to load prompt templates: %v", err))
+	}
+
+	// 加载审核词表
+	moderator, err := moderation.NewFilter(&cfg.AI.Moderation)
+	if err != nil {
+		zapLogger.Fatal(fmt.Sprintf("Failed to load moderation word lists: %v", err))
+	}
+
+	// 初始化服务（任务事件通过Redis转发给API进程推送）
+	db := pkg.GetDB()
+	aiTaskRepo := repository.NewAITaskRepository(db)
+	workRepo := repository.NewWorkRepository(db)
+	chapterRepo := repository.NewChapterRepository(db)
+	characterRepo := repository.NewCharacterRepository(db)
+	applicationRepo := repository.NewAIApplicationRepository(db)
+	candidateRepo := repository.NewAITaskCandidateRepository(db)
+	saveService := service.NewSaveService(workRepo, chapterRepo, cfg)
+	characterService := service.NewCharacterService(workRepo, characterRepo)
+	publisher := queue.NewRedisEventPublisher(pkg.GetRedis(), cfg.Queue.Name, zapLogger)
+	aiService := service.NewAIService(aiTaskRepo, workRepo, chapterRepo, characterRepo, applicationRepo, candidateRepo, saveService, characterService, taskQueue, publisher, providers, prompts, moderator, pkg.GetRedis(), cfg)
+
+	worker := queue.NewWorker(taskQueue, func(ctx context.Context, job *queue.Job) error {
+		return aiService.ProcessTask(ctx, job.TaskID)
+	}, cfg.Queue.Concurrency, zapLogger)
+
+	// 收到退出信号后停止取新任务，等待进行中的任务完成
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 恢复中断的AI任务
+	go aiService.RunRecovery(ctx)
+
+	// 为内容大幅变化的章节重新生成摘要
+	go aiService.RunSummaryScheduler(ctx)
+
+	zapLogger.Info("Worker started", zap.Int("concurrency", cfg.Queue.Concurrency))
+	worker.Run(ctx)
+	zapLogger.Info("Worker exited")
+}
@@ -14,6 +14,7 @@ type Config struct {
 	Redis    RedisConfig    `mapstructure:"redis"`
 	JWT      JWTConfig      `mapstructure:"jwt"`
 	RabbitMQ RabbitMQConfig `mapstructure:"rabbitmq"`
+	Queue    QueueConfig    `mapstructure:"queue"`
 	MinIO    MinIOConfig    `mapstructure:"minio"`
 	AI       AIConfig       `mapstructure:"ai"`
 	Log      LogConfig      `mapstructure:"log"`
@@ -73,6 +74,13 @@ type RabbitMQConfig struct {
 	Vhost    string `mapstructure:"vhost"`
 }
 
+// QueueConfig 任务队列配置
+type QueueConfig struct {
+	Driver      string `mapstructure:"driver"`      // redis（持久化，需单独运行worker）或 memory（进程内，仅用于本地开发）
+	Name        string `mapstructure:"name"`        // 队列名称（Redis键前缀）
+	Concurrency int    `mapstructure:"concurrency"` // 每个worker进程的并发处理数
+}
+
 // MinIOConfig MinIO配置
 type MinIOConfig struct {
 	Endpoint  string `mapstructure:"endpoint"`
@@ -86,14 +94,169 @@ type MinIOConfig struct {
 type AIConfig struct {
 	Claude   AIProviderConfig `mapstructure:"claude"`
 	DeepSeek AIProviderConfig `mapstructure:"deepseek"`
+	// Providers 额外的提供商，按名称注册，名称与claude、deepseek相同时覆盖对应配置
+	Providers map[string]AIProviderConfig `mapstructure:"providers"`
+	Recovery  AIRecoveryConfig            `mapstructure:"recovery"`
+	Retry     AIRetryConfig               `mapstructure:"retry"`
+	Quota     AIQuotaConfig               `mapstructure:"quota"`
+	Cache     AICacheConfig               `mapstructure:"cache"`
+	// Moderation 生成结果的敏感词审核配置
+	Moderation AIModerationConfig `mapstructure:"moderation"`
+	Prompts    AIPromptConfig     `mapstructure:"prompts"`
+	Convert    AIConvertConfig    `mapstructure:"convert"`
+	// Consistency 跨章节一致性检查配置
+	Consistency AIConsistencyConfig `mapstructure:"consistency"`
+	// Context 注入提示词的作品设定（故事圣经）配置
+	Context AIContextConfig `mapstructure:"context"`
+	// Summary 章节摘要与全书梗概的自动生成配置
+	Summary AISummaryConfig `mapstructure:"summary"`
+	// Routing 任务类型 -> 按优先级排列的提供商列表，default为未单独配置的任务类型使用的链路
+	Routing map[string][]string `mapstructure:"routing"`
+}
+
+// AIRetryConfig AI请求重试配置
+type AIRetryConfig struct {
+	MaxRetries       int `mapstructure:"max_retries"`        // 每个提供商的最大重试次数
+	InitialBackoffMs int `mapstructure:"initial_backoff_ms"` // 首次重试等待时间（毫秒），之后指数增长
+	MaxBackoffMs     int `mapstructure:"max_backoff_ms"`     // 单次等待上限（毫秒）
+}
+
+// AIConvertConfig 整部作品转换（小说转剧本等）的分段配置
+type AIConvertConfig struct {
+	ChunkChars  int `mapstructure:"chunk_chars"` // 每段原文的最大字数
+	Concurrency int `mapstructure:"concurrency"` // 同时转换的分段数
+	MaxTokens   int `mapstructure:"max_tokens"`  // 每段输出的最大token数
+}
+
+// AIConsistencyConfig 跨章节一致性检查配置
+type AIConsistencyConfig struct {
+	ChunkChars int `mapstructure:"chunk_chars"` // 每次检查的最大原文字数，超出时分段检查
+	MaxTokens  int `mapstructure:"max_tokens"`  // 每段输出的最大token数
+}
+
+// AIContextConfig 作品设定注入配置
+type AIContextConfig struct {
+	Enabled        bool `mapstructure:"enabled"`         // 关闭后所有任务都不注入，请求中的开关无效
+	MaxTokens      int  `mapstructure:"max_tokens"`      // 作品设定的token预算
+	RecentChapters int  `mapstructure:"recent_chapters"` // 附带的近期章节数
+	ExcerptChars   int  `mapstructure:"excerpt_chars"`   // 每个近期章节的摘录字数
+	// PrecedingTokens 续写时从章节读取的前文token上限（含上一章结尾）
+	PrecedingTokens int `mapstructure:"preceding_tokens"`
+}
+
+// AISummaryConfig 章节摘要与全书梗概的自动生成配置
+type AISummaryConfig struct {
+	Enabled        bool `mapstructure:"enabled"`
+	MinChangeChars int  `mapstructure:"min_change_chars"` // 自上次生成以来累计变化超过该字数时重新生成
+	Debounce       int  `mapstructure:"debounce"`         // seconds，最后一次保存后等待该时间再生成
+	PollInterval   int  `mapstructure:"poll_interval"`    // seconds，检查待生成摘要的间隔
+	InputChars     int  `mapstructure:"input_chars"`      // 生成摘要时读取的章节字数上限
+	MaxTokens      int  `mapstructure:"max_tokens"`       // 摘要和梗概输出的最大token数
+	SynopsisTokens int  `mapstructure:"synopsis_tokens"`  // 生成全书梗概时输入的章节摘要token上限
+}
+
+// AIPromptConfig 提示词模板配置
+type AIPromptConfig struct {
+	Dir            string                        `mapstructure:"dir"`             // 模板目录，覆盖或补充内置模板，为空时只使用内置模板
+	ReloadInterval int                           `mapstructure:"reload_interval"` // seconds，定期重新读取模板目录，0表示只在启动时加载
+	Versions       map[string]string             `mapstructure:"versions"`        // 模板名 -> 固定使用的版本，未配置时使用最新版本
+	Experiments    map[string]AIPromptExperiment `mapstructure:"experiments"`     // 模板名 -> 灰度实验
+}
+
+// AIPromptExperiment 提示词灰度实验，按用户ID分桶
+type AIPromptExperiment struct {
+	Version string `mapstructure:"version"`
+	Percent int    `mapstructure:"percent"` // 0-100
+}
+
+// AIQuotaConfig 每个用户的AI配额，各项为0时表示不限制
+type AIQuotaConfig struct {
+	Enabled           bool  `mapstructure:"enabled"`
+	RequestsPerMinute int   `mapstructure:"requests_per_minute"`
+	MaxConcurrent     int   `mapstructure:"max_concurrent"` // 同时排队或执行中的任务数
+	MonthlyTokens     int64 `mapstructure:"monthly_tokens"` // 每月输入+输出token预算
+	RunningTTL        int   `mapstructure:"running_ttl"`    // seconds，并发计数中任务的最长保留时间，防止异常退出的任务长期占用名额
+}
+
+// AICacheConfig 生成结果缓存配置：提供商、模型、模板版本和提示词完全相同的请求直接返回缓存结果
+type AICacheConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	TTL     int  `mapstructure:"ttl"` // seconds
+}
+
+// AIModerationConfig 生成结果审核配置：按分类加载本地词表，命中后按分类等级标记、替换或拒绝结果
+type AIModerationConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Mask    string `mapstructure:"mask"` // 替换命中词的字符，默认*
+	// Levels 等级 -> 处理方式（flag、mask、reject），默认low为flag、medium为mask、high为reject
+	Levels     map[string]string               `mapstructure:"levels"`
+	Categories map[string]AIModerationCategory `mapstructure:"categories"`
+}
+
+// AIModerationCategory 敏感词分类
+type AIModerationCategory struct {
+	Level  string   `mapstructure:"level"`  // low、medium、high或levels中配置的等级
+	Action string   `mapstructure:"action"` // 覆盖等级的处理方式
+	Words  []string `mapstructure:"words"`
+	File   string   `mapstructure:"file"` // 词表文件，每行一个词，#开头为注释
+}
+
+// AIRecoveryConfig 中断任务恢复配置
+type AIRecoveryConfig struct {
+	SweepInterval int                       `mapstructure:"sweep_interval"` // 巡检间隔（秒）
+	StaleAfter    int                       `mapstructure:"stale_after"`    // 超过该时长未更新视为中断（秒）
+	MaxAttempts   int                       `mapstructure:"max_attempts"`   // 重新入队前允许的最大执行次数
+	Action        string                    `mapstructure:"action"`         // 默认处理方式：requeue 或 fail
+	Tasks         map[string]AIRecoveryRule `mapstructure:"tasks"`          // 按任务类型覆盖
+}
+
+// AIRecoveryRule 单个任务类型的恢复规则
+type AIRecoveryRule struct {
+	Action     string `mapstructure:"action"`
+	StaleAfter int    `mapstructure:"stale_after"`
+}
+
+// RuleFor 获取指定任务类型的恢复规则（未配置的字段使用默认值）
+func (c *AIRecoveryConfig) RuleFor(taskType string) AIRecoveryRule {
+	rule := AIRecoveryRule{Action: c.Action, StaleAfter: c.StaleAfter}
+	if override, ok := c.Tasks[taskType]; ok {
+		if override.Action != "" {
+			rule.Action = override.Action
+		}
+		if override.StaleAfter > 0 {
+			rule.StaleAfter = override.StaleAfter
+		}
+	}
+	return rule
 }
 
 // AIProviderConfig AI提供商配置
 type AIProviderConfig struct {
-	APIKey    string `mapstructure:"api_key"`
-	Model     string `mapstructure:"model"`
-	MaxTokens int    `mapstructure:"max_tokens"`
-	Timeout   int    `mapstructure:"timeout"`
+	Type      string            `mapstructure:"type"`     // 驱动类型：anthropic、openai（OpenAI兼容接口）、ollama、mock
+	BaseURL   string            `mapstructure:"base_url"` // 为空时使用驱动默认地址
+	APIKey    string            `mapstructure:"api_key"`
+	Model     string            `mapstructure:"model"`
+	Headers   map[string]string `mapstructure:"headers"` // 附加请求头
+	MaxTokens int               `mapstructure:"max_tokens"`
+	Timeout   int               `mapstructure:"timeout"`
+	// 价格（美元/百万token），用于计算任务成本
+	InputPrice  float64 `mapstructure:"input_price"`
+	OutputPrice float64 `mapstructure:"output_price"`
+	// Mock mock驱动的模拟行为，其他驱动忽略
+	Mock AIMockConfig `mapstructure:"mock"`
+}
+
+// AIMockConfig mock驱动配置：按提示词生成确定的文本，可模拟延迟、流式输出、限流和失败
+type AIMockConfig struct {
+	LatencyMs      int `mapstructure:"latency_ms"`       // 开始输出前的延迟
+	ChunkChars     int `mapstructure:"chunk_chars"`      // 流式输出每段的字数
+	ChunkDelayMs   int `mapstructure:"chunk_delay_ms"`   // 流式输出每段之间的间隔
+	OutputChars    int `mapstructure:"output_chars"`     // 输出字数（不超过maxTokens）
+	FailEvery      int `mapstructure:"fail_every"`       // 每N次请求返回一次500，0为不模拟
+	RateLimitEvery int `mapstructure:"rate_limit_every"` // 每N次请求返回一次429，0为不模拟
+	RetryAfterMs   int `mapstructure:"retry_after_ms"`   // 429响应建议的等待时间
+	// Responses 提示词包含key时原样返回value，用于为结构化输出的任务提供固定结果（按key排序匹配）
+	Responses map[string]string `mapstructure:"responses"`
 }
 
 // LogConfig 日志配置
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"errors"
+	"io"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jugo/backend/internal/dto"
@@ -31,7 +35,7 @@ func (h *AIHandler) Continue(c *gin.Context) {
 	}
 
 	// 从上下文获取用户ID
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		response.Error(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -39,6 +43,21 @@ func (h *AIHandler) Continue(c *gin.Context) {
 
 	resp, err := h.aiService.Continue(userID.(uint), &req)
 	if err != nil {
+		if handleQuotaError(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrUnknownProvider) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err == service.ErrChapterNotFound {
+			response.Error(c, http.StatusNotFound, "Chapter not found")
+			return
+		}
+		if err == service.ErrInvalidTextRange {
+			response.Error(c, http.StatusBadRequest, "Cursor exceeds chapter content")
+			return
+		}
 		if err == service.ErrWorkNotFound {
 			response.Error(c, http.StatusNotFound, "Work not found")
 			return
@@ -62,7 +81,7 @@ func (h *AIHandler) Polish(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		response.Error(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -70,6 +89,13 @@ func (h *AIHandler) Polish(c *gin.Context) {
 
 	resp, err := h.aiService.Polish(userID.(uint), &req)
 	if err != nil {
+		if handleQuotaError(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrUnknownProvider) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		if err == service.ErrWorkNotFound {
 			response.Error(c, http.StatusNotFound, "Work not found")
 			return
@@ -93,7 +119,7 @@ func (h *AIHandler) Expand(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		response.Error(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -101,6 +127,13 @@ func (h *AIHandler) Expand(c *gin.Context) {
 
 	resp, err := h.aiService.Expand(userID.(uint), &req)
 	if err != nil {
+		if handleQuotaError(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrUnknownProvider) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		if err == service.ErrWorkNotFound {
 			response.Error(c, http.StatusNotFound, "Work not found")
 			return
@@ -124,7 +157,7 @@ func (h *AIHandler) Rewrite(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		response.Error(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -132,6 +165,13 @@ func (h *AIHandler) Rewrite(c *gin.Context) {
 
 	resp, err := h.aiService.Rewrite(userID.(uint), &req)
 	if err != nil {
+		if handleQuotaError(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrUnknownProvider) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		if err == service.ErrWorkNotFound {
 			response.Error(c, http.StatusNotFound, "Work not found")
 			return
@@ -156,7 +196,7 @@ func (h *AIHandler) GetTaskStatus(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		response.Error(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -187,7 +227,7 @@ func (h *AIHandler) GenerateOutline(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		response.Error(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -195,6 +235,9 @@ func (h *AIHandler) GenerateOutline(c *gin.Context) {
 
 	resp, err := h.aiService.GenerateOutline(userID.(uint), &req)
 	if err != nil {
+		if handleQuotaError(c, err) {
+			return
+		}
 		if err == service.ErrWorkNotFound {
 			response.Error(c, http.StatusNotFound, "Work not found")
 			return
@@ -218,7 +261,7 @@ func (h *AIHandler) ConvertNovelToScreenplay(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		response.Error(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -226,6 +269,13 @@ func (h *AIHandler) ConvertNovelToScreenplay(c *gin.Context) {
 
 	resp, err := h.aiService.ConvertNovelToScreenplay(userID.(uint), &req)
 	if err != nil {
+		if handleQuotaError(c, err) {
+			return
+		}
+		if err == service.ErrEmptyWork {
+			response.Error(c, http.StatusBadRequest, "Work has no chapter content")
+			return
+		}
 		if err == service.ErrWorkNotFound {
 			response.Error(c, http.StatusNotFound, "Work not found")
 			return
@@ -249,7 +299,7 @@ func (h *AIHandler) ConvertScreenplayToNovel(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		response.Error(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -257,6 +307,51 @@ func (h *AIHandler) ConvertScreenplayToNovel(c *gin.Context) {
 
 	resp, err := h.aiService.ConvertScreenplayToNovel(userID.(uint), &req)
 	if err != nil {
+		if handleQuotaError(c, err) {
+			return
+		}
+		if err == service.ErrEmptyWork {
+			response.Error(c, http.StatusBadRequest, "Work has no chapter content")
+			return
+		}
+		if err == service.ErrWorkNotFound {
+			response.Error(c, http.StatusNotFound, "Work not found")
+			return
+		}
+		if err == service.ErrUnauthorized {
+			response.Error(c, http.StatusForbidden, "Forbidden")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to create AI task: "+err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// CheckConsistency 跨章节一致性检查
+func (h *AIHandler) CheckConsistency(c *gin.Context) {
+	var req dto.ConsistencyCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resp, err := h.aiService.CheckConsistency(userID.(uint), &req)
+	if err != nil {
+		if handleQuotaError(c, err) {
+			return
+		}
+		if err == service.ErrEmptyWork {
+			response.Error(c, http.StatusBadRequest, "Work has no chapter content")
+			return
+		}
 		if err == service.ErrWorkNotFound {
 			response.Error(c, http.StatusNotFound, "Work not found")
 			return
@@ -271,3 +366,419 @@ func (h *AIHandler) ConvertScreenplayToNovel(c *gin.Context) {
 
 	response.Success(c, resp)
 }
+
+// ExpandSnowflake 由雪花写作法的当前步骤生成下一步
+func (h *AIHandler) ExpandSnowflake(c *gin.Context) {
+	var req dto.SnowflakeExpandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resp, err := h.aiService.ExpandSnowflake(userID.(uint), &req)
+	if err != nil {
+		if handleQuotaError(c, err) {
+			return
+		}
+		if err == service.ErrSnowflakeStepIncomplete {
+			response.Error(c, http.StatusConflict, "Snowflake step is not completed")
+			return
+		}
+		if err == service.ErrWorkNotFound {
+			response.Error(c, http.StatusNotFound, "Work not found")
+			return
+		}
+		if err == service.ErrUnauthorized {
+			response.Error(c, http.StatusForbidden, "Forbidden")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to create AI task: "+err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// GenerateCharacters AI角色生成
+func (h *AIHandler) GenerateCharacters(c *gin.Context) {
+	var req dto.CharacterGenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resp, err := h.aiService.GenerateCharacters(userID.(uint), &req)
+	if err != nil {
+		if handleQuotaError(c, err) {
+			return
+		}
+		if err == service.ErrWorkNotFound {
+			response.Error(c, http.StatusNotFound, "Work not found")
+			return
+		}
+		if err == service.ErrUnauthorized {
+			response.Error(c, http.StatusForbidden, "Forbidden")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to create AI task: "+err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// ListTasks 获取AI任务历史
+func (h *AIHandler) ListTasks(c *gin.Context) {
+	var params dto.AITaskQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters: "+err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resp, err := h.aiService.ListTasks(userID.(uint), &params)
+	if err != nil {
+		if err == service.ErrInvalidAITaskQuery {
+			response.Error(c, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to list AI tasks: "+err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// DeleteTask 删除任务（未结束的任务会先取消）
+func (h *AIHandler) DeleteTask(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.aiService.DeleteTask(userID.(uint), uint(taskID)); err != nil {
+		if err == service.ErrAITaskNotFound {
+			response.Error(c, http.StatusNotFound, "Task not found")
+			return
+		}
+		if err == service.ErrUnauthorized {
+			response.Error(c, http.StatusForbidden, "Forbidden")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to delete task: "+err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, "Task deleted successfully", nil)
+}
+
+// CancelTask 取消任务
+func (h *AIHandler) CancelTask(c *gin.Context) {
+	taskIDStr := c.Param("id")
+	taskID, err := strconv.ParseUint(taskIDStr, 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resp, err := h.aiService.CancelTask(userID.(uint), uint(taskID))
+	if err != nil {
+		if err == service.ErrAITaskNotFound {
+			response.Error(c, http.StatusNotFound, "Task not found")
+			return
+		}
+		if err == service.ErrUnauthorized {
+			response.Error(c, http.StatusForbidden, "Forbidden")
+			return
+		}
+		if err == service.ErrAITaskNotCancellable {
+			response.Error(c, http.StatusConflict, "Task already finished")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to cancel task: "+err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// ChooseCandidate 选择候选结果
+func (h *AIHandler) ChooseCandidate(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+	candidateID, err := strconv.ParseUint(c.Param("candidateId"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid candidate ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resp, err := h.aiService.ChooseCandidate(userID.(uint), uint(taskID), uint(candidateID))
+	if err != nil {
+		if err == service.ErrAITaskNotFound {
+			response.Error(c, http.StatusNotFound, "Task not found")
+			return
+		}
+		if err == service.ErrAITaskCandidateNotFound {
+			response.Error(c, http.StatusNotFound, "Candidate not found")
+			return
+		}
+		if err == service.ErrUnauthorized {
+			response.Error(c, http.StatusForbidden, "Forbidden")
+			return
+		}
+		if err == service.ErrAITaskCandidateFailed {
+			response.Error(c, http.StatusConflict, "Candidate generation failed")
+			return
+		}
+		if err == service.ErrAITaskNotCompleted {
+			response.Error(c, http.StatusConflict, "Task is not completed")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to choose candidate: "+err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// ApplyTask 将任务结果应用到章节（preview时只返回差异）
+func (h *AIHandler) ApplyTask(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+
+	var req dto.AIApplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resp, err := h.aiService.ApplyTask(userID.(uint), uint(taskID), &req)
+	if err != nil {
+		if err == service.ErrAITaskNotFound {
+			response.Error(c, http.StatusNotFound, "Task not found")
+			return
+		}
+		if err == service.ErrChapterNotFound {
+			response.Error(c, http.StatusNotFound, "Chapter not found")
+			return
+		}
+		if err == service.ErrUnauthorized {
+			response.Error(c, http.StatusForbidden, "Forbidden")
+			return
+		}
+		if err == service.ErrInvalidTextRange {
+			response.Error(c, http.StatusBadRequest, "Range exceeds chapter content")
+			return
+		}
+		if err == service.ErrAITaskNotApplicable {
+			response.Error(c, http.StatusConflict, "Task result cannot be applied")
+			return
+		}
+		if err == service.ErrChapterChanged {
+			response.Error(c, http.StatusConflict, "Chapter content has changed")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to apply task result: "+err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// UndoApplication 撤销一次结果应用
+func (h *AIHandler) UndoApplication(c *gin.Context) {
+	applicationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid application ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resp, err := h.aiService.UndoApplication(userID.(uint), uint(applicationID))
+	if err != nil {
+		if err == service.ErrAIApplicationNotFound {
+			response.Error(c, http.StatusNotFound, "Application not found")
+			return
+		}
+		if err == service.ErrChapterNotFound {
+			response.Error(c, http.StatusNotFound, "Chapter not found")
+			return
+		}
+		if err == service.ErrUnauthorized {
+			response.Error(c, http.StatusForbidden, "Forbidden")
+			return
+		}
+		if err == service.ErrAIApplicationUndone {
+			response.Error(c, http.StatusConflict, "Application already undone")
+			return
+		}
+		if err == service.ErrAIApplicationConflict {
+			response.Error(c, http.StatusConflict, "Applied text has been modified")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to undo application: "+err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// GetUsage 获取当前用户的AI用量统计
+func (h *AIHandler) GetUsage(c *gin.Context) {
+	var query dto.AIUsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters: "+err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resp, err := h.aiService.GetUsage(userID.(uint), &query)
+	if err != nil {
+		if err == service.ErrInvalidUsageRange {
+			response.Error(c, http.StatusBadRequest, "Invalid date range")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to get AI usage: "+err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// handleQuotaError 超出配额时返回429及恢复时间，返回是否已处理
+func handleQuotaError(c *gin.Context, err error) bool {
+	var quotaErr *service.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(time.Until(quotaErr.ResetAt).Seconds()))
+	if retryAfter < 0 {
+		retryAfter = 0
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	response.TooManyRequests(c, "AI quota exceeded: "+quotaErr.Limit, &dto.QuotaExceededResponse{
+		Limit:      quotaErr.Limit,
+		Max:        quotaErr.Max,
+		Used:       quotaErr.Used,
+		ResetAt:    quotaErr.ResetAt,
+		RetryAfter: retryAfter,
+	})
+	return true
+}
+
+// MaterializeOutline 按大纲任务结果批量创建章节
+func (h *AIHandler) MaterializeOutline(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+
+	// 请求体可选，为空时创建全部章节
+	var req dto.MaterializeOutlineRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resp, err := h.aiService.MaterializeOutline(userID.(uint), uint(taskID), &req)
+	if err != nil {
+		if err == service.ErrAITaskNotFound {
+			response.Error(c, http.StatusNotFound, "Task not found")
+			return
+		}
+		if err == service.ErrWorkNotFound {
+			response.Error(c, http.StatusNotFound, "Work not found")
+			return
+		}
+		if err == service.ErrUnauthorized {
+			response.Error(c, http.StatusForbidden, "Forbidden")
+			return
+		}
+		if err == service.ErrInvalidOutlineChapters {
+			response.Error(c, http.StatusBadRequest, "Invalid outline chapter selection")
+			return
+		}
+		if err == service.ErrOutlineNotMaterializable {
+			response.Error(c, http.StatusConflict, "Task is not a completed outline")
+			return
+		}
+		if err == service.ErrOutlineMaterialized {
+			response.Error(c, http.StatusConflict, "Outline chapters already created")
+			return
+		}
+		if err == service.ErrModerationRejected {
+			response.Error(c, http.StatusUnprocessableEntity, "Outline rejected by content moderation")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to create chapters: "+err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, "Chapters created successfully", resp)
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/service"
+	"github.com/jugo/backend/pkg/response"
+)
+
+// SnowflakeHandler 雪花写作法处理器
+type SnowflakeHandler struct {
+	snowflakeService service.SnowflakeService
+}
+
+// NewSnowflakeHandler 创建雪花写作法处理器
+func NewSnowflakeHandler(snowflakeService service.SnowflakeService) *SnowflakeHandler {
+	return &SnowflakeHandler{
+		snowflakeService: snowflakeService,
+	}
+}
+
+// Get 获取雪花写作法进度
+func (h *SnowflakeHandler) Get(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	workID, err := strconv.ParseUint(c.Param("workId"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "Invalid work ID")
+		return
+	}
+
+	resp, err := h.snowflakeService.Get(userID.(uint), uint(workID))
+	if err != nil {
+		if handleSnowflakeError(c, err) {
+			return
+		}
+		response.InternalServerError(c, "Failed to get snowflake")
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// UpdateStep 保存雪花写作法的一个步骤
+func (h *SnowflakeHandler) UpdateStep(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	workID, err := strconv.ParseUint(c.Param("workId"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "Invalid work ID")
+		return
+	}
+
+	step, err := strconv.Atoi(c.Param("step"))
+	if err != nil {
+		response.BadRequest(c, "Invalid step")
+		return
+	}
+
+	var req dto.SnowflakeStep
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request parameters")
+		return
+	}
+
+	resp, err := h.snowflakeService.UpdateStep(userID.(uint), uint(workID), step, &req)
+	if err != nil {
+		if handleSnowflakeError(c, err) {
+			return
+		}
+		response.InternalServerError(c, "Failed to update snowflake step")
+		return
+	}
+
+	response.SuccessWithMessage(c, "Snowflake step updated successfully", resp)
+}
+
+// CreateCharacters 将第三步的角色设定创建为角色
+func (h *SnowflakeHandler) CreateCharacters(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	workID, err := strconv.ParseUint(c.Param("workId"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "Invalid work ID")
+		return
+	}
+
+	resp, err := h.snowflakeService.CreateCharacters(userID.(uint), uint(workID))
+	if err != nil {
+		if handleSnowflakeError(c, err) {
+			return
+		}
+		response.InternalServerError(c, "Failed to create characters")
+		return
+	}
+
+	response.SuccessWithMessage(c, "Characters created successfully", resp)
+}
+
+// handleSnowflakeError 处理雪花写作法服务的通用错误，返回是否已处理
+func handleSnowflakeError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrWorkNotFound):
+		response.NotFound(c, "Work not found")
+	case errors.Is(err, service.ErrUnauthorized):
+		response.Forbidden(c, "Access denied")
+	case errors.Is(err, service.ErrInvalidSnowflakeStep):
+		response.BadRequest(c, err.Error())
+	case errors.Is(err, service.ErrSnowflakeStepIncomplete):
+		response.Error(c, http.StatusConflict, "Previous snowflake step is not completed")
+	default:
+		return false
+	}
+	return true
+}
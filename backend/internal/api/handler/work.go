@@ -150,6 +150,43 @@ func (h *WorkHandler) Update(c *gin.Context) {
 	response.SuccessWithMessage(c, "Work updated successfully", workResp)
 }
 
+// UpdateAllowedTerms 更新作品的审核白名单
+func (h *WorkHandler) UpdateAllowedTerms(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Unauthorized")
+		return
+	}
+
+	workID, err := strconv.ParseUint(c.Param("workId"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "Invalid work ID")
+		return
+	}
+
+	var req dto.UpdateAllowedTermsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request parameters")
+		return
+	}
+
+	workResp, err := h.workService.UpdateAllowedTerms(userID.(uint), uint(workID), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrWorkNotFound) {
+			response.NotFound(c, "Work not found")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Forbidden(c, "Access denied")
+			return
+		}
+		response.InternalServerError(c, "Failed to update allowed terms")
+		return
+	}
+
+	response.SuccessWithMessage(c, "Allowed terms updated successfully", workResp)
+}
+
 // Delete 删除作品
 func (h *WorkHandler) Delete(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -1,19 +1,26 @@
 package router
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
 	"github.com/jugo/backend/config"
 	"github.com/jugo/backend/internal/api/handler"
 	"github.com/jugo/backend/internal/api/middleware"
 	"github.com/jugo/backend/internal/api/websocket"
+	"github.com/jugo/backend/internal/moderation"
+	"github.com/jugo/backend/internal/prompt"
+	"github.com/jugo/backend/internal/queue"
 	"github.com/jugo/backend/internal/repository"
 	"github.com/jugo/backend/internal/service"
+	"github.com/jugo/backend/pkg/ai"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 // Setup 设置路由
-func Setup(logger *zap.Logger, db *gorm.DB, cfg *config.Config) *gin.Engine {
+func Setup(logger *zap.Logger, db *gorm.DB, rdb *redis.Client, taskQueue queue.Queue, providers *ai.Registry, prompts *prompt.Store, moderator *moderation.Filter, cfg *config.Config) *gin.Engine {
 	r := gin.New()
 
 	// 全局中间件
@@ -34,12 +41,40 @@ func Setup(logger *zap.Logger, db *gorm.DB, cfg *config.Config) *gin.Engine {
 	chapterRepo := repository.NewChapterRepository(db)
 	characterRepo := repository.NewCharacterRepository(db)
 	aiTaskRepo := repository.NewAITaskRepository(db)
+	aiApplicationRepo := repository.NewAIApplicationRepository(db)
+	aiCandidateRepo := repository.NewAITaskCandidateRepository(db)
 	workService := service.NewWorkService(workRepo, chapterRepo)
 	chapterService := service.NewChapterService(workRepo, chapterRepo)
 	characterService := service.NewCharacterService(workRepo, characterRepo)
+	snowflakeService := service.NewSnowflakeService(workRepo, characterRepo, characterService)
 	exportService := service.NewExportService(workRepo, chapterRepo, characterRepo)
-	saveService := service.NewSaveService(workRepo, chapterRepo)
-	aiService := service.NewAIService(aiTaskRepo, workRepo, cfg)
+	saveService := service.NewSaveService(workRepo, chapterRepo, cfg)
+
+	// 初始化 WebSocket Handler（AI任务事件通过WebSocket推送给用户）
+	wsHandler := websocket.NewHandler(saveService, cfg)
+	aiNotifier := websocket.NewAINotifier(wsHandler.GetHub())
+
+	aiService := service.NewAIService(aiTaskRepo, workRepo, chapterRepo, characterRepo, aiApplicationRepo, aiCandidateRepo, saveService, characterService, taskQueue, aiNotifier, providers, prompts, moderator, rdb, cfg)
+
+	if cfg.Queue.Driver == queue.DriverMemory {
+		// 没有独立worker时由本进程恢复中断的任务（启动时立即执行一次，之后定期巡检）并安排章节摘要，
+		// 使用Redis队列时这些巡检只在worker进程中运行
+		go aiService.RunRecovery(context.Background())
+		go aiService.RunSummaryScheduler(context.Background())
+
+		// 内存队列只能在本进程内消费
+		worker := queue.NewWorker(taskQueue, func(ctx context.Context, job *queue.Job) error {
+			return aiService.ProcessTask(ctx, job.TaskID)
+		}, cfg.Queue.Concurrency, logger)
+		go worker.Run(context.Background())
+	} else {
+		// 转发独立worker进程发布的AI任务事件
+		go func() {
+			if err := queue.RelayEvents(context.Background(), rdb, cfg.Queue.Name, aiNotifier, logger); err != nil {
+				logger.Error("AI task event relay stopped", zap.Error(err))
+			}
+		}()
+	}
 
 	// 初始化处理器
 	authHandler := handler.NewAuthHandler(userService)
@@ -47,13 +82,11 @@ func Setup(logger *zap.Logger, db *gorm.DB, cfg *config.Config) *gin.Engine {
 	workHandler := handler.NewWorkHandler(workService)
 	chapterHandler := handler.NewChapterHandler(chapterService)
 	characterHandler := handler.NewCharacterHandler(characterService)
+	snowflakeHandler := handler.NewSnowflakeHandler(snowflakeService)
 	exportHandler := handler.NewExportHandler(exportService)
 	saveHandler := handler.NewSaveHandler(saveService)
 	aiHandler := handler.NewAIHandler(aiService)
 
-	// 初始化 WebSocket Handler
-	wsHandler := websocket.NewHandler(saveService, cfg)
-
 	// API v1 路由组
 	v1 := r.Group("/api/v1")
 	{
@@ -72,6 +105,7 @@ func Setup(logger *zap.Logger, db *gorm.DB, cfg *config.Config) *gin.Engine {
 		{
 			users.GET("/me", userHandler.GetProfile)
 			users.PATCH("/me", userHandler.UpdateProfile)
+			users.GET("/me/ai-usage", aiHandler.GetUsage)
 		}
 
 		// 作品相关路由（需要认证）
@@ -95,6 +129,14 @@ func Setup(logger *zap.Logger, db *gorm.DB, cfg *config.Config) *gin.Engine {
 			works.POST("/:workId/characters", characterHandler.Create)
 			works.GET("/:workId/characters", characterHandler.List)
 
+			// 雪花写作法相关路由
+			works.GET("/:workId/snowflake", snowflakeHandler.Get)
+			works.PUT("/:workId/snowflake/steps/:step", snowflakeHandler.UpdateStep)
+			works.POST("/:workId/snowflake/characters", snowflakeHandler.CreateCharacters)
+
+			// 审核白名单
+			works.PUT("/:workId/allowed-terms", workHandler.UpdateAllowedTerms)
+
 			// 导出相关路由
 			works.POST("/:id/export", exportHandler.Export)
 
@@ -123,7 +165,17 @@ func Setup(logger *zap.Logger, db *gorm.DB, cfg *config.Config) *gin.Engine {
 			ai.POST("/outline", aiHandler.GenerateOutline)
 			ai.POST("/convert/novel-to-screenplay", aiHandler.ConvertNovelToScreenplay)
 			ai.POST("/convert/screenplay-to-novel", aiHandler.ConvertScreenplayToNovel)
+			ai.POST("/consistency-check", aiHandler.CheckConsistency)
+			ai.POST("/snowflake/expand", aiHandler.ExpandSnowflake)
+			ai.POST("/characters", aiHandler.GenerateCharacters)
+			ai.GET("/tasks", aiHandler.ListTasks)
 			ai.GET("/tasks/:id", aiHandler.GetTaskStatus)
+			ai.DELETE("/tasks/:id", aiHandler.DeleteTask)
+			ai.POST("/tasks/:id/cancel", aiHandler.CancelTask)
+			ai.POST("/tasks/:id/candidates/:candidateId/choose", aiHandler.ChooseCandidate)
+			ai.POST("/tasks/:id/apply", aiHandler.ApplyTask)
+			ai.POST("/applications/:id/undo", aiHandler.UndoApplication)
+			ai.POST("/tasks/:id/materialize", aiHandler.MaterializeOutline)
 		}
 	}
 
@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"time"
 
@@ -19,6 +20,9 @@ const (
 	maxMessageSize = 512 * 1024 // 512KB
 )
 
+// ErrSendBufferFull 客户端发送缓冲区已满（客户端读取过慢）
+var ErrSendBufferFull = errors.New("websocket send buffer full")
+
 // Client WebSocket客户端
 type Client struct {
 	hub    *Hub
@@ -113,7 +117,7 @@ func (c *Client) writePump() {
 	}
 }
 
-// SendMessage 发送消息给客户端
+// SendMessage 发送消息给客户端，缓冲区已满时不阻塞，返回ErrSendBufferFull
 func (c *Client) SendMessage(msg *Message) error {
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -124,6 +128,6 @@ func (c *Client) SendMessage(msg *Message) error {
 	case c.send <- data:
 		return nil
 	default:
-		return nil // 通道已满，丢弃消息
+		return ErrSendBufferFull
 	}
 }
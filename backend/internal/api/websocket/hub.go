@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"sync"
 )
@@ -68,7 +69,10 @@ func (h *Hub) Run() {
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				delete(h.userClients, client.userID)
+				// 同一用户可能已建立了新连接
+				if h.userClients[client.userID] == client {
+					delete(h.userClients, client.userID)
+				}
 				close(client.send)
 				log.Printf("Client unregistered: userID=%d", client.userID)
 			}
@@ -99,16 +103,27 @@ func (h *Hub) Run() {
 }
 
 // SendToUser 发送消息给指定用户
+//
+// 客户端发送缓冲区已满时断开该连接而不是丢弃消息：丢失流式片段或结束事件后客户端的状态已不完整，
+// 重新连接后应通过任务状态接口重新同步。
 func (h *Hub) SendToUser(userID uint, msg *Message) error {
+	// 持有读锁直到发送完成，避免客户端注销时向已关闭的通道写入
+	// （SendMessage为非阻塞写入，不会长时间占用锁）
 	h.mu.RLock()
 	client, ok := h.userClients[userID]
-	h.mu.RUnlock()
-
 	if !ok {
+		h.mu.RUnlock()
 		return nil // 用户不在线，忽略
 	}
+	err := client.SendMessage(msg)
+	h.mu.RUnlock()
 
-	return client.SendMessage(msg)
+	if errors.Is(err, ErrSendBufferFull) {
+		log.Printf("Client send buffer full, disconnecting: userID=%d", userID)
+		// Run在注销时需要写锁，异步提交避免阻塞调用方
+		go func() { h.unregister <- client }()
+	}
+	return err
 }
 
 // GetClientCount 获取在线客户端数量
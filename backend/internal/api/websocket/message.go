@@ -9,6 +9,10 @@ const (
 	MessageTypeAutosave    MessageType = "autosave"
 	MessageTypeAutosaveAck MessageType = "autosave_ack"
 	MessageTypeAIProgress  MessageType = "ai_progress"
+	MessageTypeAIChunk     MessageType = "ai_chunk"
+	MessageTypeAICompleted MessageType = "ai_completed"
+	MessageTypeAIFailed    MessageType = "ai_failed"
+	MessageTypeAICancelled MessageType = "ai_cancelled"
 	MessageTypePing        MessageType = "ping"
 	MessageTypePong        MessageType = "pong"
 	MessageTypeError       MessageType = "error"
@@ -25,7 +29,11 @@ type Message struct {
 	SavedAt   *time.Time  `json:"savedAt,omitempty"`
 	Words     int         `json:"words,omitempty"`
 	TaskID    string      `json:"taskId,omitempty"`
+	TaskType  string      `json:"taskType,omitempty"`
+	Status    string      `json:"status,omitempty"`
 	Progress  int         `json:"progress,omitempty"`
+	Delta     string      `json:"delta,omitempty"`
+	Offset    int         `json:"offset,omitempty"`
 	Message   string      `json:"message,omitempty"`
 	Error     string      `json:"error,omitempty"`
 }
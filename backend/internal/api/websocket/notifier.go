@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/service"
+)
+
+// AINotifier 通过WebSocket向用户推送AI任务事件，实现service.AITaskNotifier接口
+type AINotifier struct {
+	hub *Hub
+}
+
+// NewAINotifier 创建AI任务事件推送器
+func NewAINotifier(hub *Hub) *AINotifier {
+	return &AINotifier{hub: hub}
+}
+
+// NotifyAITask 推送AI任务事件给任务所有者
+func (n *AINotifier) NotifyAITask(userID uint, event *dto.AITaskEvent) {
+	msg := &Message{
+		WorkID:   event.WorkID,
+		TaskID:   strconv.FormatUint(uint64(event.TaskID), 10),
+		TaskType: event.TaskType,
+		Status:   event.Status,
+		Progress: event.Progress,
+	}
+
+	switch event.Event {
+	case service.AITaskEventChunk:
+		msg.Type = MessageTypeAIChunk
+		msg.Delta = event.Delta
+		msg.Offset = event.Offset
+	case service.AITaskEventCompleted:
+		msg.Type = MessageTypeAICompleted
+		msg.Content = event.Result
+		msg.Success = true
+	case service.AITaskEventFailed:
+		msg.Type = MessageTypeAIFailed
+		msg.Error = event.Error
+	case service.AITaskEventCancelled:
+		msg.Type = MessageTypeAICancelled
+	default:
+		msg.Type = MessageTypeAIProgress
+	}
+
+	if err := n.hub.SendToUser(userID, msg); err != nil {
+		log.Printf("Failed to send AI task event: taskID=%d, err=%v", event.TaskID, err)
+	}
+}
@@ -1,18 +1,64 @@
 package dto
 
-import "time"
+import (
+	"time"
+
+	"github.com/jugo/backend/internal/model"
+)
+
+// AIContextOptions 作品设定注入选项，嵌入到各AI请求中
+type AIContextOptions struct {
+	UseContext *bool `json:"useContext,omitempty"` // 是否注入作品设定，默认注入
+}
+
+// ContextEnabled 请求是否需要注入作品设定
+func (o AIContextOptions) ContextEnabled() bool {
+	return o.UseContext == nil || *o.UseContext
+}
+
+// AICacheOptions 生成结果缓存选项，嵌入到各AI请求中
+type AICacheOptions struct {
+	NoCache bool `json:"noCache,omitempty"` // 不使用缓存的结果，重新生成并刷新缓存
+}
+
+// AICandidateOptions 多候选生成选项，嵌入到续写、润色、扩写、改写请求中
+type AICandidateOptions struct {
+	Candidates int      `json:"candidates,omitempty" binding:"omitempty,min=1,max=5"` // 候选数量，默认1
+	Providers  []string `json:"providers,omitempty" binding:"omitempty,max=5"`        // 依次轮流使用的提供商，为空时使用任务类型的默认链路
+}
+
+// CandidateCount 需要生成的候选数量
+func (o AICandidateOptions) CandidateCount() int {
+	if o.Candidates == 0 && len(o.Providers) > 0 {
+		return len(o.Providers)
+	}
+	if o.Candidates == 0 {
+		return 1
+	}
+	return o.Candidates
+}
 
 // ContinueRequest AI续写请求
 type ContinueRequest struct {
+	AIContextOptions
+	AICacheOptions
+	AICandidateOptions
 	WorkID  uint   `json:"workId" binding:"required"`
 	Type    string `json:"type" binding:"required,oneof=novel screenplay"` // novel or screenplay
-	Context string `json:"context" binding:"required"`                     // 前文内容
+	Context string `json:"context" binding:"required_without=ChapterID"`   // 前文内容，指定章节时由服务端读取
 	Length  int    `json:"length" binding:"required,min=100,max=5000"`     // 续写长度
 	Style   string `json:"style"`                                          // 风格要求
+
+	// 从章节中读取前文：Cursor为光标在章节纯文本中的字偏移（与结果应用接口一致），为空时从章节末尾续写
+	ChapterID uint `json:"chapterId,omitempty"`
+	Cursor    *int `json:"cursor,omitempty" binding:"omitempty,min=0"`
 }
 
 // PolishRequest AI润色请求
 type PolishRequest struct {
+	AIContextOptions
+	AICacheOptions
+	AICandidateOptions
 	WorkID  uint   `json:"workId" binding:"required"`
 	Content string `json:"content" binding:"required"` // 需要润色的内容
 	Style   string `json:"style"`                      // 风格要求
@@ -20,6 +66,9 @@ type PolishRequest struct {
 
 // ExpandRequest AI扩写请求
 type ExpandRequest struct {
+	AIContextOptions
+	AICacheOptions
+	AICandidateOptions
 	WorkID  uint   `json:"workId" binding:"required"`
 	Content string `json:"content" binding:"required"`        // 需要扩写的内容
 	Length  int    `json:"length" binding:"required,min=100"` // 扩写后的目标长度
@@ -28,6 +77,9 @@ type ExpandRequest struct {
 
 // RewriteRequest AI改写请求
 type RewriteRequest struct {
+	AIContextOptions
+	AICacheOptions
+	AICandidateOptions
 	WorkID  uint   `json:"workId" binding:"required"`
 	Content string `json:"content" binding:"required"` // 需要改写的内容
 	Style   string `json:"style"`                      // 改写风格
@@ -43,17 +95,161 @@ type AITaskResponse struct {
 
 // TaskStatusResponse 任务状态响应
 type TaskStatusResponse struct {
-	TaskID      uint       `json:"taskId"`
-	Status      string     `json:"status"`
-	Progress    int        `json:"progress"` // 0-100
-	Result      string     `json:"result,omitempty"`
-	Error       string     `json:"error,omitempty"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	TaskID      uint                    `json:"taskId"`
+	Status      string                  `json:"status"`
+	Progress    int                     `json:"progress"` // 0-100
+	Provider    string                  `json:"provider,omitempty"`
+	Model       string                  `json:"model,omitempty"`
+	Usage       *AIUsage                `json:"usage,omitempty"`
+	CacheHit    bool                    `json:"cacheHit,omitempty"`   // 结果来自缓存
+	Moderation  *model.AITaskModeration `json:"moderation,omitempty"` // 结果审核发现
+	Result      string                  `json:"result,omitempty"`     // 多候选时为选中的候选
+	Candidates  []AICandidate           `json:"candidates,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	CreatedAt   time.Time               `json:"createdAt"`
+	CompletedAt *time.Time              `json:"completedAt,omitempty"`
+}
+
+// AIApplyRequest 将任务结果应用到章节的请求
+//
+// Start、End为章节纯文本中的字偏移（段落之间以一个换行分隔，与编辑器选区一致），
+// 结果替换[Start, End)范围内的文本，Start等于End时为插入。
+type AIApplyRequest struct {
+	ChapterID   uint   `json:"chapterId" binding:"required"`
+	Start       int    `json:"start" binding:"min=0"`
+	End         int    `json:"end" binding:"min=0,gtefield=Start"`
+	Preview     bool   `json:"preview"`     // 只返回差异，不写入章节
+	ContentHash string `json:"contentHash"` // 预览时返回的章节内容哈希，不一致说明章节已被修改
+}
+
+// AIApplyResponse 应用（或撤销）结果
+type AIApplyResponse struct {
+	ApplicationID uint          `json:"applicationId,omitempty"`
+	ChapterID     uint          `json:"chapterId"`
+	Preview       bool          `json:"preview"`
+	Start         int           `json:"start"`
+	End           int           `json:"end"`
+	Removed       string        `json:"removed"`
+	Inserted      string        `json:"inserted"`
+	Diff          []DiffSegment `json:"diff"`
+	ContentHash   string        `json:"contentHash"` // 预览时为当前章节内容哈希，写入后为新内容哈希
+	Words         int           `json:"words,omitempty"`
+	SavedAt       *time.Time    `json:"savedAt,omitempty"`
+}
+
+// DiffSegment 差异片段
+type DiffSegment struct {
+	Op   string `json:"op"` // equal, insert, delete
+	Text string `json:"text"`
+}
+
+// AITaskQueryParams AI任务历史查询参数，日期格式为YYYY-MM-DD（含首尾两天）
+type AITaskQueryParams struct {
+	WorkID uint   `form:"workId" binding:"omitempty,min=1"`
+	Type   string `form:"type"`
+	Status string `form:"status" binding:"omitempty,oneof=pending processing completed failed cancelled"`
+	From   string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To     string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	Page   int    `form:"page" binding:"omitempty,min=1"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// AITaskListItem AI任务列表项，完整结果通过任务详情获取
+type AITaskListItem struct {
+	TaskID        uint       `json:"taskId"`
+	WorkID        uint       `json:"workId"`
+	Type          string     `json:"type"`
+	Status        string     `json:"status"`
+	Progress      int        `json:"progress"`
+	Provider      string     `json:"provider,omitempty"`
+	Model         string     `json:"model,omitempty"`
+	Usage         *AIUsage   `json:"usage,omitempty"`
+	CacheHit      bool       `json:"cacheHit,omitempty"`
+	ResultPreview string     `json:"resultPreview,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	CompletedAt   *time.Time `json:"completedAt,omitempty"`
+}
+
+// AITaskListResponse AI任务列表响应
+type AITaskListResponse struct {
+	Tasks      []AITaskListItem `json:"tasks"`
+	Pagination Pagination       `json:"pagination"`
+}
+
+// AICandidate 候选结果
+type AICandidate struct {
+	CandidateID uint     `json:"candidateId"`
+	Index       int      `json:"index"`
+	Provider    string   `json:"provider,omitempty"`
+	Model       string   `json:"model,omitempty"`
+	Text        string   `json:"text,omitempty"`
+	Error       string   `json:"error,omitempty"`
+	Usage       *AIUsage `json:"usage,omitempty"`
+	Chosen      bool     `json:"chosen"`
+}
+
+// AdaptationResult 改编生成的新作品（剧本转小说任务的结果）
+type AdaptationResult struct {
+	WorkID   uint                `json:"workId"`
+	Title    string              `json:"title"`
+	Chapters []AdaptationChapter `json:"chapters"`
+}
+
+// AdaptationChapter 改编生成的章节
+type AdaptationChapter struct {
+	ChapterID uint   `json:"chapterId"`
+	Title     string `json:"title"`
+	Order     int    `json:"order"`
+	Words     int    `json:"words"`
+}
+
+// AIUsage token用量与成本（美元）
+type AIUsage struct {
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// QuotaExceededResponse 超出AI配额时的响应数据
+type QuotaExceededResponse struct {
+	Limit      string    `json:"limit"` // requests_per_minute、concurrent_tasks、monthly_tokens
+	Max        int64     `json:"max"`
+	Used       int64     `json:"used"`
+	ResetAt    time.Time `json:"resetAt"`
+	RetryAfter int       `json:"retryAfter"` // 秒
+}
+
+// AIUsageQuery AI用量查询参数，日期格式为YYYY-MM-DD（含首尾两天）
+type AIUsageQuery struct {
+	Period string `form:"period" binding:"omitempty,oneof=daily monthly"`
+	From   string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To     string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+}
+
+// AIUsageItem 按周期和任务类型汇总的用量
+type AIUsageItem struct {
+	Period       string  `json:"period,omitempty"` // 按日为2006-01-02，按月为2006-01
+	TaskType     string  `json:"taskType,omitempty"`
+	Tasks        int64   `json:"tasks"`
+	InputTokens  int64   `json:"inputTokens"`
+	OutputTokens int64   `json:"outputTokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// AIUsageResponse AI用量统计响应
+type AIUsageResponse struct {
+	Period string        `json:"period"`
+	From   string        `json:"from"`
+	To     string        `json:"to"`
+	Items  []AIUsageItem `json:"items"`
+	Total  AIUsageItem   `json:"total"` // 汇总，Period和TaskType为空
 }
 
 // OutlineRequest AI大纲生成请求
 type OutlineRequest struct {
+	AIContextOptions
+	AICacheOptions
 	WorkID      uint   `json:"workId" binding:"required"`
 	Topic       string `json:"topic" binding:"required,min=5,max=500"`       // 主题
 	Genre       string `json:"genre" binding:"required"`                     // 类型（都市、玄幻、科幻等）
@@ -61,16 +257,132 @@ type OutlineRequest struct {
 	Style       string `json:"style"`                                        // 风格要求
 }
 
+// OutlineResult 结构化大纲（大纲生成任务的结果）
+type OutlineResult struct {
+	Synopsis   string             `json:"synopsis"`             // 故事梗概
+	Characters []OutlineCharacter `json:"characters,omitempty"` // 主要角色
+	Chapters   []OutlineChapter   `json:"chapters"`
+}
+
+// OutlineCharacter 大纲中的角色
+type OutlineCharacter struct {
+	Name        string `json:"name"`
+	Role        string `json:"role,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// OutlineChapter 大纲中的一章
+type OutlineChapter struct {
+	Order      int      `json:"order"`
+	Title      string   `json:"title"`
+	Synopsis   string   `json:"synopsis"`             // 章节梗概
+	Beats      []string `json:"beats,omitempty"`      // 关键情节点
+	Characters []string `json:"characters,omitempty"` // 出场角色
+}
+
+// MaterializeOutlineRequest 按大纲创建章节请求
+type MaterializeOutlineRequest struct {
+	Chapters []int `json:"chapters,omitempty" binding:"omitempty,dive,min=1"` // 只创建大纲中指定序号的章节，为空时创建全部章节
+}
+
+// MaterializeOutlineResponse 按大纲创建章节的结果
+type MaterializeOutlineResponse struct {
+	WorkID      uint                `json:"workId"`
+	NumChapters int                 `json:"numChapters"` // 作品当前章节总数
+	Chapters    []AdaptationChapter `json:"chapters"`    // 新创建的章节
+}
+
+// CharacterGenerateRequest AI角色生成请求
+type CharacterGenerateRequest struct {
+	AIContextOptions
+	AICacheOptions
+	WorkID uint   `json:"workId" binding:"required"`
+	Count  int    `json:"count" binding:"omitempty,min=1,max=5"`                            // 生成数量，默认1
+	Role   string `json:"role" binding:"omitempty,oneof=protagonist antagonist supporting"` // 角色类型，为空时由模型决定
+	Brief  string `json:"brief" binding:"omitempty,max=500"`                                // 补充要求，如“与主角亦敌亦友”
+	Save   bool   `json:"save,omitempty"`                                                   // 是否直接创建为角色
+}
+
+// CharacterGenerateResult 角色生成任务的结果
+type CharacterGenerateResult struct {
+	Characters []GeneratedCharacter `json:"characters"`
+}
+
+// GeneratedCharacter 生成的角色
+type GeneratedCharacter struct {
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+	Description string `json:"description"`
+	Motivation  string `json:"motivation"`
+	CharacterID uint   `json:"characterId,omitempty"` // 已创建的角色ID，未保存时为空
+}
+
+// ChapterSummaryRequest 章节摘要任务参数（由后台在章节内容大幅变化后创建）
+type ChapterSummaryRequest struct {
+	WorkID    uint `json:"workId"`
+	ChapterID uint `json:"chapterId"`
+}
+
+// ContextEnabled 章节正文已直接写入提示词，不再注入作品设定
+func (r ChapterSummaryRequest) ContextEnabled() bool {
+	return false
+}
+
 // NovelToScreenplayRequest 小说转剧本请求
 type NovelToScreenplayRequest struct {
+	AICacheOptions
 	WorkID         uint `json:"workId" binding:"required"`
-	TargetDuration int  `json:"targetDuration,omitempty"` // 目标时长（分钟）
-	NumScenes      int  `json:"numScenes,omitempty"`      // 场景数量
+	TargetDuration int  `json:"targetDuration,omitempty" binding:"omitempty,min=1,max=600"` // 目标时长（分钟）
+	NumScenes      int  `json:"numScenes,omitempty" binding:"omitempty,min=1,max=500"`      // 场景数量
 }
 
 // ScreenplayToNovelRequest 剧本转小说请求
 type ScreenplayToNovelRequest struct {
+	AICacheOptions
 	WorkID         uint `json:"workId" binding:"required"`
-	NumChapters    int  `json:"numChapters,omitempty"`    // 章节数
-	WordPerChapter int  `json:"wordPerChapter,omitempty"` // 每章字数
+	NumChapters    int  `json:"numChapters,omitempty" binding:"omitempty,min=1,max=100"`      // 章节数
+	WordPerChapter int  `json:"wordPerChapter,omitempty" binding:"omitempty,min=1,max=10000"` // 每章字数
+}
+
+// ConsistencyCheckRequest 跨章节一致性检查请求
+type ConsistencyCheckRequest struct {
+	AICacheOptions
+	WorkID     uint   `json:"workId" binding:"required"`
+	ChapterIDs []uint `json:"chapterIds,omitempty"` // 只检查指定章节，为空时检查全部章节
+}
+
+// ConsistencyReport 一致性检查结果（一致性检查任务的结果）
+type ConsistencyReport struct {
+	CheckedChapters int                  `json:"checkedChapters"`
+	Findings        []ConsistencyFinding `json:"findings"`
+}
+
+// ConsistencyFinding 一处不一致
+type ConsistencyFinding struct {
+	Type        string               `json:"type"`     // character, timeline, plot_thread, other
+	Severity    string               `json:"severity"` // high, medium, low
+	Description string               `json:"description"`
+	ChapterIDs  []uint               `json:"chapterIds"`
+	Excerpts    []ConsistencyExcerpt `json:"excerpts"`
+}
+
+// ConsistencyExcerpt 引用的原文片段
+type ConsistencyExcerpt struct {
+	ChapterID uint   `json:"chapterId"`
+	Quote     string `json:"quote"`
+	Verified  bool   `json:"verified"` // 引文是否确实出现在该章节中
+}
+
+// AITaskEvent AI任务实时事件
+type AITaskEvent struct {
+	Event    string `json:"event"` // progress, chunk, completed, failed, cancelled
+	TaskID   uint   `json:"taskId"`
+	WorkID   uint   `json:"workId"`
+	TaskType string `json:"taskType"`
+	Status   string `json:"status"`
+	Progress int    `json:"progress"`
+	Delta    string `json:"delta,omitempty"`  // 本次新增的文本片段
+	Offset   int    `json:"offset,omitempty"` // 片段在完整结果中的起始字符（rune）偏移，客户端可据此检测丢包
+	Result   string `json:"result,omitempty"`
+	Error    string `json:"error,omitempty"`
 }
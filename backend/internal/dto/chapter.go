@@ -6,43 +6,52 @@ import "time"
 type CreateChapterRequest struct {
 	Title    string `json:"title" binding:"required,min=1,max=200"`
 	Content  string `json:"content" binding:"omitempty"`
+	Synopsis string `json:"synopsis" binding:"omitempty"`
 	OrderNum int    `json:"order" binding:"required,min=1"`
 }
 
 // UpdateChapterRequest 更新章节请求
 type UpdateChapterRequest struct {
-	Title    string `json:"title" binding:"omitempty,min=1,max=200"`
-	Content  string `json:"content" binding:"omitempty"`
-	OrderNum int    `json:"order" binding:"omitempty,min=1"`
-	Status   string `json:"status" binding:"omitempty,oneof=draft completed"`
+	Title    string  `json:"title" binding:"omitempty,min=1,max=200"`
+	Content  string  `json:"content" binding:"omitempty"`
+	Synopsis *string `json:"synopsis" binding:"omitempty"` // nil表示不修改，空字符串表示清空
+	OrderNum int     `json:"order" binding:"omitempty,min=1"`
+	Status   string  `json:"status" binding:"omitempty,oneof=draft completed"`
 }
 
 // ChapterResponse 章节响应
 type ChapterResponse struct {
-	ChapterID uint      `json:"chapterId"`
-	WorkID    uint      `json:"workId"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	Words     int       `json:"words"`
-	Order     int       `json:"order"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ChapterID    uint       `json:"chapterId"`
+	WorkID       uint       `json:"workId"`
+	Title        string     `json:"title"`
+	Content      string     `json:"content"`
+	Synopsis     string     `json:"synopsis,omitempty"`
+	Summary      string     `json:"summary,omitempty"`      // AI生成的章节摘要
+	SummarizedAt *time.Time `json:"summarizedAt,omitempty"` // 摘要生成时间
+	Words        int        `json:"words"`
+	Order        int        `json:"order"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
 }
 
 // ChapterListItem 章节列表项
 type ChapterListItem struct {
-	ChapterID uint      `json:"chapterId"`
-	WorkID    uint      `json:"workId"`
-	Title     string    `json:"title"`
-	Words     int       `json:"words"`
-	Order     int       `json:"order"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ChapterID    uint       `json:"chapterId"`
+	WorkID       uint       `json:"workId"`
+	Title        string     `json:"title"`
+	Synopsis     string     `json:"synopsis,omitempty"`
+	Summary      string     `json:"summary,omitempty"`      // AI生成的章节摘要
+	SummarizedAt *time.Time `json:"summarizedAt,omitempty"` // 摘要生成时间
+	Words        int        `json:"words"`
+	Order        int        `json:"order"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
 }
 
 // ChapterListResponse 章节列表响应
 type ChapterListResponse struct {
-	Chapters []ChapterListItem `json:"chapters"`
+	Chapters     []ChapterListItem `json:"chapters"`
+	WorkSynopsis string            `json:"workSynopsis,omitempty"` // AI滚动更新的全书梗概
 }
@@ -0,0 +1,48 @@
+package dto
+
+// SnowflakeCharacter 雪花写作法第三步的角色设定
+type SnowflakeCharacter struct {
+	Name        string `json:"name"`
+	Role        string `json:"role,omitempty"`        // protagonist/antagonist/supporting
+	Description string `json:"description,omitempty"` // 角色简介
+	Motivation  string `json:"motivation,omitempty"`  // 动机
+	Goal        string `json:"goal,omitempty"`        // 目标
+	Conflict    string `json:"conflict,omitempty"`    // 冲突
+	Epiphany    string `json:"epiphany,omitempty"`    // 顿悟/成长
+}
+
+// SnowflakeChapter 雪花写作法第四步的章节规划
+type SnowflakeChapter struct {
+	Title    string `json:"title"`
+	Synopsis string `json:"synopsis"`
+}
+
+// SnowflakeStep 雪花写作法单个步骤的内容：第一、二步为Text，第三步为Characters，第四步为Chapters
+type SnowflakeStep struct {
+	Step       int                  `json:"step,omitempty"`
+	Text       string               `json:"text,omitempty"`
+	Characters []SnowflakeCharacter `json:"characters,omitempty"`
+	Chapters   []SnowflakeChapter   `json:"chapters,omitempty"`
+}
+
+// SnowflakeResponse 雪花写作法进度
+type SnowflakeResponse struct {
+	WorkID         uint                 `json:"workId"`
+	Step1          string               `json:"step1"`          // 一句话核心概括
+	Step2          string               `json:"step2"`          // 一段话故事梗概
+	Step3          []SnowflakeCharacter `json:"step3"`          // 角色设定
+	Step4          []SnowflakeChapter   `json:"step4"`          // 章节规划
+	CompletedSteps int                  `json:"completedSteps"` // 从第一步起连续完成的步骤数
+}
+
+// SnowflakeExpandRequest 由第Step步生成第Step+1步的请求
+type SnowflakeExpandRequest struct {
+	AICacheOptions
+	WorkID uint `json:"workId" binding:"required"`
+	Step   int  `json:"step" binding:"required,min=1,max=3"`
+}
+
+// ContextEnabled 各步骤内容已直接写入提示词，不再注入作品设定
+func (r SnowflakeExpandRequest) ContextEnabled() bool {
+	return false
+}
@@ -28,6 +28,11 @@ type UpdateWorkRequest struct {
 	Metadata       string `json:"metadata" binding:"omitempty"` // JSON string
 }
 
+// UpdateAllowedTermsRequest 更新作品审核白名单请求（整体替换，传空列表清空）
+type UpdateAllowedTermsRequest struct {
+	Terms []string `json:"terms" binding:"max=500,dive,min=1,max=50"`
+}
+
 // WorkResponse 作品响应
 type WorkResponse struct {
 	WorkID         uint                `json:"workId"`
@@ -40,23 +45,26 @@ type WorkResponse struct {
 	NumChapters    int                 `json:"numChapters"`
 	WordPerChapter int                 `json:"wordPerChapter"`
 	CoverImage     string              `json:"coverImage,omitempty"`
+	Synopsis       string              `json:"synopsis,omitempty"` // AI滚动更新的全书梗概
 	Metadata       *model.WorkMetadata `json:"metadata,omitempty"`
+	SourceWorkID   *uint               `json:"sourceWorkId,omitempty"` // 改编来源作品
 	CreatedAt      time.Time           `json:"createdAt"`
 	UpdatedAt      time.Time           `json:"updatedAt"`
 }
 
 // WorkListItem 作品列表项
 type WorkListItem struct {
-	WorkID      uint      `json:"workId"`
-	Type        string    `json:"type"`
-	Title       string    `json:"title"`
-	Genre       string    `json:"genre,omitempty"`
-	Status      string    `json:"status"`
-	Words       int       `json:"words"`
-	NumChapters int       `json:"numChapters"`
-	CoverImage  string    `json:"coverImage,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	WorkID       uint      `json:"workId"`
+	Type         string    `json:"type"`
+	Title        string    `json:"title"`
+	Genre        string    `json:"genre,omitempty"`
+	Status       string    `json:"status"`
+	Words        int       `json:"words"`
+	NumChapters  int       `json:"numChapters"`
+	CoverImage   string    `json:"coverImage,omitempty"`
+	SourceWorkID *uint     `json:"sourceWorkId,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
 }
 
 // WorkListResponse 作品列表响应
@@ -80,4 +88,6 @@ type WorkQueryParams struct {
 	Page   int    `form:"page" binding:"omitempty,min=1"`
 	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
 	Sort   string `form:"sort" binding:"omitempty,oneof=updatedAt createdAt words title"`
+	// SourceWorkID 只返回由该作品改编而来的作品
+	SourceWorkID uint `form:"sourceWorkId" binding:"omitempty,min=1"`
 }
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// AIApplication AI任务结果应用到章节的记录，用于撤销
+type AIApplication struct {
+	BaseModel
+	TaskID    uint `gorm:"not null;index" json:"taskId"`
+	UserID    uint `gorm:"not null;index" json:"userId"`
+	WorkID    uint `gorm:"not null" json:"workId"`
+	ChapterID uint `gorm:"not null;index" json:"chapterId"`
+
+	// 被替换的纯文本范围（按字计，段落之间以换行分隔）
+	Start    int    `gorm:"column:range_start;not null" json:"start"`
+	End      int    `gorm:"column:range_end;not null" json:"end"`
+	Removed  string `gorm:"type:text" json:"removed"`  // 被替换的原文
+	Inserted string `gorm:"type:text" json:"inserted"` // 写入的新文本
+
+	// 应用前的完整章节内容，以及应用后内容的哈希：章节未再修改时撤销可原样恢复
+	ContentBefore string `gorm:"type:longtext" json:"-"`
+	ContentHash   string `gorm:"type:varchar(64)" json:"-"`
+
+	UndoneAt *time.Time `json:"undoneAt,omitempty"`
+}
+
+// TableName 指定表名
+func (AIApplication) TableName() string {
+	return "ai_applications"
+}
@@ -1,6 +1,9 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -17,8 +20,27 @@ const (
 	AITaskTypeOutline           AITaskType = "outline"             // 大纲生成
 	AITaskTypeNovelToScreenplay AITaskType = "novel_to_screenplay" // 小说转剧本
 	AITaskTypeScreenplayToNovel AITaskType = "screenplay_to_novel" // 剧本转小说
+	AITaskTypeConsistencyCheck  AITaskType = "consistency_check"   // 跨章节一致性检查
+	AITaskTypeSnowflake         AITaskType = "snowflake"           // 雪花写作法步骤扩展
+	AITaskTypeCharacter         AITaskType = "character"           // 角色生成
+	AITaskTypeChapterSummary    AITaskType = "chapter_summary"     // 章节摘要（后台自动生成）
 )
 
+// AITaskTypes 所有AI任务类型
+var AITaskTypes = []AITaskType{
+	AITaskTypeContinue,
+	AITaskTypePolish,
+	AITaskTypeExpand,
+	AITaskTypeRewrite,
+	AITaskTypeOutline,
+	AITaskTypeNovelToScreenplay,
+	AITaskTypeScreenplayToNovel,
+	AITaskTypeConsistencyCheck,
+	AITaskTypeSnowflake,
+	AITaskTypeCharacter,
+	AITaskTypeChapterSummary,
+}
+
 // AITaskStatus AI任务状态
 type AITaskStatus string
 
@@ -27,8 +49,46 @@ const (
 	AITaskStatusProcessing AITaskStatus = "processing" // 处理中
 	AITaskStatusCompleted  AITaskStatus = "completed"  // 已完成
 	AITaskStatusFailed     AITaskStatus = "failed"     // 失败
+	AITaskStatusCancelled  AITaskStatus = "cancelled"  // 已取消
 )
 
+// IsActive 任务是否仍在等待或执行中
+func (s AITaskStatus) IsActive() bool {
+	return s == AITaskStatusPending || s == AITaskStatusProcessing
+}
+
+// AITaskModeration 任务结果的审核记录
+type AITaskModeration struct {
+	Action   string              `json:"action"` // 最严重的处理方式：flag、mask、reject
+	Findings []ModerationFinding `json:"findings"`
+}
+
+// ModerationFinding 命中的敏感词
+type ModerationFinding struct {
+	Category string `json:"category"`
+	Level    string `json:"level"`
+	Action   string `json:"action"`
+	Term     string `json:"term"`
+	Count    int    `json:"count"`
+}
+
+// Scan 实现 sql.Scanner 接口
+func (m *AITaskModeration) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to unmarshal AITaskModeration value")
+	}
+	return json.Unmarshal(bytes, m)
+}
+
+// Value 实现 driver.Valuer 接口
+func (m AITaskModeration) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
 // AITask AI任务模型
 type AITask struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -42,19 +102,43 @@ type AITask struct {
 	Type   AITaskType   `gorm:"type:varchar(20);not null" json:"type"`
 	Status AITaskStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
 
+	// 实际生成结果的AI提供商
+	Provider string `gorm:"type:varchar(50)" json:"provider,omitempty"`
+
+	// 使用的提示词模板版本（多个模板时以逗号分隔）
+	PromptVersion string `gorm:"type:varchar(255)" json:"promptVersion,omitempty"`
+
+	// 用量与成本（多次调用的任务为累计值，成本单位为美元）
+	Model        string  `gorm:"type:varchar(100)" json:"model,omitempty"`
+	InputTokens  int     `gorm:"default:0" json:"inputTokens"`
+	OutputTokens int     `gorm:"default:0" json:"outputTokens"`
+	Cost         float64 `gorm:"type:decimal(12,6);default:0" json:"cost"`
+
+	// 结果（部分）来自生成结果缓存，缓存命中的调用不计用量
+	CacheHit bool `gorm:"default:false" json:"cacheHit"`
+
 	// 任务参数（JSON格式存储）
-	Parameters string `gorm:"type:text" json:"parameters"`
+	Parameters string `gorm:"type:mediumtext" json:"parameters"`
 
 	// 任务结果
-	Result string `gorm:"type:text" json:"result"`
+	Result string `gorm:"type:mediumtext" json:"result"`
 	Error  string `gorm:"type:text" json:"error"`
 
+	// 结果审核发现，未启用审核或没有命中时为空
+	Moderation *AITaskModeration `gorm:"type:json" json:"moderation,omitempty"`
+
 	// 进度信息
 	Progress int `gorm:"default:0" json:"progress"` // 0-100
 
+	// 被worker认领执行的次数
+	Attempts int `gorm:"default:0" json:"attempts"`
+
 	// 完成时间
 	CompletedAt *time.Time `json:"completedAt,omitempty"`
 
+	// 大纲任务已生成章节的时间
+	MaterializedAt *time.Time `json:"materializedAt,omitempty"`
+
 	// 关联
 	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Work *Work `gorm:"foreignKey:WorkID" json:"work,omitempty"`
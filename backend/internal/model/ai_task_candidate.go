@@ -0,0 +1,25 @@
+package model
+
+// AITaskCandidate 一次请求生成多个候选结果时的单个候选
+type AITaskCandidate struct {
+	BaseModel
+	TaskID uint `gorm:"not null;index" json:"taskId"`
+	Index  int  `gorm:"column:candidate_index;not null" json:"index"` // 从0开始
+
+	Provider string `gorm:"type:varchar(50)" json:"provider,omitempty"`
+	Model    string `gorm:"type:varchar(100)" json:"model,omitempty"`
+	Text     string `gorm:"type:text" json:"text"`
+	Error    string `gorm:"type:text" json:"error,omitempty"`
+
+	InputTokens  int     `gorm:"default:0" json:"inputTokens"`
+	OutputTokens int     `gorm:"default:0" json:"outputTokens"`
+	Cost         float64 `gorm:"type:decimal(12,6);default:0" json:"cost"`
+
+	// 被选中的候选同时写入任务结果，应用到章节时使用
+	Chosen bool `gorm:"default:false" json:"chosen"`
+}
+
+// TableName 指定表名
+func (AITaskCandidate) TableName() string {
+	return "ai_task_candidates"
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // ChapterStatus 章节状态
 type ChapterStatus string
 
@@ -14,10 +16,17 @@ type Chapter struct {
 	WorkID   uint          `gorm:"not null;index" json:"workId"`
 	Title    string        `gorm:"type:varchar(200);not null" json:"title"`
 	Content  string        `gorm:"type:longtext" json:"content"`
+	Synopsis string        `gorm:"type:text" json:"synopsis,omitempty"` // 章节梗概
 	Words    int           `gorm:"default:0" json:"words"`
 	OrderNum int           `gorm:"not null;index:idx_work_order" json:"order"`
 	Status   ChapterStatus `gorm:"type:varchar(20);default:'draft'" json:"status"`
 
+	// AI生成的章节摘要，由后台任务单独更新
+	Summary        string     `gorm:"type:text" json:"summary,omitempty"`
+	SummaryChanges int        `gorm:"not null;default:0" json:"-"` // 自上次生成摘要以来累计变化的字数
+	SummaryDueAt   *time.Time `gorm:"index" json:"-"`              // 计划重新生成摘要的时间（防抖）
+	SummarizedAt   *time.Time `json:"summarizedAt,omitempty"`
+
 	// 关联
 	Work Work `gorm:"foreignKey:WorkID" json:"-"`
 }
@@ -26,14 +26,23 @@ const (
 // WorkMetadata 作品元数据
 type WorkMetadata struct {
 	Snowflake *SnowflakeData `json:"snowflake,omitempty"`
+	// AllowedTerms 作品的审核白名单，落在这些词范围内的敏感词命中会被忽略
+	AllowedTerms []string `json:"allowedTerms,omitempty"`
 }
 
 // SnowflakeData 雪花写作法数据
 type SnowflakeData struct {
-	Step1 string                   `json:"step1,omitempty"` // 核心概括
-	Step2 string                   `json:"step2,omitempty"` // 扩展大纲
-	Step3 []map[string]interface{} `json:"step3,omitempty"` // 角色设定
-	Step4 bool                     `json:"step4,omitempty"` // 是否开始正文创作
+	Step1    string                   `json:"step1,omitempty"`    // 核心概括
+	Step2    string                   `json:"step2,omitempty"`    // 扩展大纲
+	Step3    []map[string]interface{} `json:"step3,omitempty"`    // 角色设定
+	Step4    bool                     `json:"step4,omitempty"`    // 是否已完成章节规划，可以开始正文创作
+	Chapters []SnowflakeChapter       `json:"chapters,omitempty"` // 章节规划（第四步）
+}
+
+// SnowflakeChapter 雪花写作法章节规划中的一章
+type SnowflakeChapter struct {
+	Title    string `json:"title"`
+	Synopsis string `json:"synopsis"`
 }
 
 // Scan 实现 sql.Scanner 接口
@@ -60,6 +69,7 @@ type Work struct {
 	Type           WorkType     `gorm:"type:varchar(20);not null;index" json:"type"`
 	Title          string       `gorm:"type:varchar(200);not null" json:"title"`
 	Topic          string       `gorm:"type:text" json:"topic"`
+	Synopsis       string       `gorm:"type:text" json:"synopsis,omitempty"` // 由各章摘要滚动更新的全书梗概
 	Genre          string       `gorm:"type:varchar(50)" json:"genre"`
 	Status         WorkStatus   `gorm:"type:varchar(20);default:'draft';index" json:"status"`
 	Words          int          `gorm:"default:0" json:"words"`
@@ -67,6 +77,7 @@ type Work struct {
 	WordPerChapter int          `gorm:"default:0" json:"wordPerChapter"`
 	CoverImage     string       `gorm:"type:varchar(255)" json:"coverImage,omitempty"`
 	Metadata       WorkMetadata `gorm:"type:json" json:"metadata,omitempty"`
+	SourceWorkID   *uint        `gorm:"index" json:"sourceWorkId,omitempty"` // 改编来源作品
 
 	// 关联
 	User       User        `gorm:"foreignKey:UserID" json:"-"`
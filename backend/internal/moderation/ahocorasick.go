@@ -0,0 +1,102 @@
+package moderation
+
+import "unicode"
+
+// Match 一次匹配，Start、End为文本中的字（rune）偏移，匹配范围为[Start, End)
+type Match struct {
+	Pattern int // 命中的词在构建时的下标
+	Start   int
+	End     int
+}
+
+// acNode 自动机节点
+type acNode struct {
+	next   map[rune]int
+	fail   int
+	output []int // 以该节点结尾的词（含通过失败指针可达的词）
+}
+
+// automaton Aho-Corasick多模式匹配自动机，匹配不区分大小写
+type automaton struct {
+	nodes   []acNode
+	lengths []int // 各词的字数
+}
+
+// newAutomaton 由词表构建自动机，空词被忽略
+func newAutomaton(patterns []string) *automaton {
+	a := &automaton{
+		nodes:   []acNode{{next: map[rune]int{}}},
+		lengths: make([]int, len(patterns)),
+	}
+
+	// 构建字典树
+	for i, p := range patterns {
+		runes := []rune(p)
+		a.lengths[i] = len(runes)
+		if len(runes) == 0 {
+			continue
+		}
+		cur := 0
+		for _, r := range runes {
+			r = unicode.ToLower(r)
+			child, ok := a.nodes[cur].next[r]
+			if !ok {
+				child = len(a.nodes)
+				a.nodes = append(a.nodes, acNode{next: map[rune]int{}})
+				a.nodes[cur].next[r] = child
+			}
+			cur = child
+		}
+		a.nodes[cur].output = append(a.nodes[cur].output, i)
+	}
+
+	// 按层构建失败指针
+	queue := make([]int, 0, len(a.nodes))
+	for _, child := range a.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range a.nodes[cur].next {
+			fail := a.nodes[cur].fail
+			for fail > 0 {
+				if _, ok := a.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = a.nodes[fail].fail
+			}
+			if target, ok := a.nodes[fail].next[r]; ok && target != child {
+				a.nodes[child].fail = target
+			}
+			a.nodes[child].output = append(a.nodes[child].output, a.nodes[a.nodes[child].fail].output...)
+			queue = append(queue, child)
+		}
+	}
+	return a
+}
+
+// findAll 查找文本中所有词的所有出现位置（包括相互重叠的匹配）
+func (a *automaton) findAll(text string) []Match {
+	var matches []Match
+	cur := 0
+	pos := 0
+	for _, r := range text {
+		r = unicode.ToLower(r)
+		for {
+			if next, ok := a.nodes[cur].next[r]; ok {
+				cur = next
+				break
+			}
+			if cur == 0 {
+				break
+			}
+			cur = a.nodes[cur].fail
+		}
+		pos++
+		for _, p := range a.nodes[cur].output {
+			matches = append(matches, Match{Pattern: p, Start: pos - a.lengths[p], End: pos})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,60 @@
+package moderation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAutomatonFindAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		text     string
+		want     []Match
+	}{
+		{
+			name:     "overlapping patterns via failure links",
+			patterns: []string{"he", "she", "his", "hers"},
+			text:     "ushers",
+			want:     []Match{{Pattern: 1, Start: 1, End: 4}, {Pattern: 0, Start: 2, End: 4}, {Pattern: 3, Start: 2, End: 6}},
+		},
+		{
+			name:     "case insensitive",
+			patterns: []string{"Abc"},
+			text:     "xABCx",
+			want:     []Match{{Pattern: 0, Start: 1, End: 4}},
+		},
+		{
+			name:     "rune offsets for CJK text",
+			patterns: []string{"杀手", "杀手锏"},
+			text:     "他的杀手锏",
+			want:     []Match{{Pattern: 0, Start: 2, End: 4}, {Pattern: 1, Start: 2, End: 5}},
+		},
+		{
+			name:     "repeated matches",
+			patterns: []string{"aa"},
+			text:     "aaa",
+			want:     []Match{{Pattern: 0, Start: 0, End: 2}, {Pattern: 0, Start: 1, End: 3}},
+		},
+		{
+			name:     "empty pattern ignored",
+			patterns: []string{"", "a"},
+			text:     "ba",
+			want:     []Match{{Pattern: 1, Start: 1, End: 2}},
+		},
+		{
+			name:     "no match",
+			patterns: []string{"abc"},
+			text:     "abd",
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newAutomaton(tt.patterns).findAll(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findAll(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jugo/backend/config"
+)
+
+// Action 命中敏感词后的处理方式
+type Action string
+
+const (
+	ActionNone   Action = ""
+	ActionFlag   Action = "flag"   // 仅记录
+	ActionMask   Action = "mask"   // 替换为掩码字符
+	ActionReject Action = "reject" // 拒绝整个结果
+)
+
+// 分类等级
+const (
+	LevelLow    = "low"
+	LevelMedium = "medium"
+	LevelHigh   = "high"
+)
+
+// defaultLevelActions 未配置levels时各等级的处理方式
+var defaultLevelActions = map[string]Action{
+	LevelLow:    ActionFlag,
+	LevelMedium: ActionMask,
+	LevelHigh:   ActionReject,
+}
+
+// severity 处理方式的严重程度，多个发现以最严重的为准
+var severity = map[Action]int{
+	ActionNone:   0,
+	ActionFlag:   1,
+	ActionMask:   2,
+	ActionReject: 3,
+}
+
+const defaultMask = '*'
+
+// Severity 处理方式的严重程度：reject > mask > flag > 无
+func (a Action) Severity() int {
+	return severity[a]
+}
+
+// Finding 一个命中的词
+type Finding struct {
+	Category string `json:"category"`
+	Level    string `json:"level"`
+	Action   Action `json:"action"`
+	Term     string `json:"term"`
+	Count    int    `json:"count"`
+}
+
+// Result 审核结果
+type Result struct {
+	Action   Action    // 最严重的处理方式，没有命中时为空
+	Text     string    // 处理后的文本（mask的词已替换，reject时为原文）
+	Findings []Finding // 按分类、词排序
+}
+
+// term 词表中的一个词
+type term struct {
+	word     string
+	category string
+	level    string
+	action   Action
+}
+
+// Filter 基于本地词表的内容审核，nil表示未启用（Check直接放行）
+type Filter struct {
+	terms   []term
+	matcher *automaton
+	mask    rune
+}
+
+// NewFilter 按配置加载各分类的词表，未启用时返回nil
+func NewFilter(cfg *config.AIModerationConfig) (*Filter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	levelActions := make(map[string]Action, len(defaultLevelActions))
+	for level, action := range defaultLevelActions {
+		levelActions[level] = action
+	}
+	for level, action := range cfg.Levels {
+		if err := validateAction(Action(action)); err != nil {
+			return nil, fmt.Errorf("moderation level %s: %w", level, err)
+		}
+		levelActions[level] = Action(action)
+	}
+
+	f := &Filter{mask: defaultMask}
+	if cfg.Mask != "" {
+		f.mask, _ = utf8.DecodeRuneInString(cfg.Mask)
+	}
+
+	// 按分类名排序，保证同一个词出现在多个分类时结果稳定
+	names := make([]string, 0, len(cfg.Categories))
+	for name := range cfg.Categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		category := cfg.Categories[name]
+		action, ok := levelActions[category.Level]
+		if !ok {
+			return nil, fmt.Errorf("moderation category %s: unknown level %q", name, category.Level)
+		}
+		if category.Action != "" {
+			if err := validateAction(Action(category.Action)); err != nil {
+				return nil, fmt.Errorf("moderation category %s: %w", name, err)
+			}
+			action = Action(category.Action)
+		}
+
+		words := category.Words
+		if category.File != "" {
+			fileWords, err := loadWordFile(category.File)
+			if err != nil {
+				return nil, fmt.Errorf("moderation category %s: %w", name, err)
+			}
+			words = append(append([]string{}, words...), fileWords...)
+		}
+		for _, w := range words {
+			if w = strings.TrimSpace(w); w != "" {
+				f.terms = append(f.terms, term{word: w, category: name, level: category.Level, action: action})
+			}
+		}
+	}
+
+	patterns := make([]string, len(f.terms))
+	for i, t := range f.terms {
+		patterns[i] = t.word
+	}
+	f.matcher = newAutomaton(patterns)
+	return f, nil
+}
+
+// validateAction 检查配置的处理方式
+func validateAction(action Action) error {
+	switch action {
+	case ActionFlag, ActionMask, ActionReject:
+		return nil
+	}
+	return fmt.Errorf("unknown action %q", action)
+}
+
+// loadWordFile 读取词表文件，每行一个词，空行和#开头的行被忽略
+func loadWordFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open word list: %w", err)
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read word list: %w", err)
+	}
+	return words, nil
+}
+
+// Check 审核文本，allowed为作品的白名单：完全落在白名单词出现范围内的命中会被忽略（如白名单中的"杀手锏"不会因"杀手"被拦截）
+func (f *Filter) Check(text string, allowed []string) *Result {
+	result := &Result{Text: text}
+	if f == nil || len(f.terms) == 0 || text == "" {
+		return result
+	}
+
+	matches := f.matcher.findAll(text)
+	if len(matches) == 0 {
+		return result
+	}
+	var allowedMatches []Match
+	if len(allowed) > 0 {
+		allowedMatches = newAutomaton(allowed).findAll(text)
+	}
+
+	type key struct{ category, term string }
+	counts := make(map[key]*Finding)
+	var masked []bool
+	for _, m := range matches {
+		if covered(m, allowedMatches) {
+			continue
+		}
+		t := f.terms[m.Pattern]
+		k := key{t.category, t.word}
+		if finding, ok := counts[k]; ok {
+			finding.Count++
+		} else {
+			counts[k] = &Finding{Category: t.category, Level: t.level, Action: t.action, Term: t.word, Count: 1}
+		}
+		if t.action.Severity() > result.Action.Severity() {
+			result.Action = t.action
+		}
+		if t.action == ActionMask {
+			if masked == nil {
+				masked = make([]bool, utf8.RuneCountInString(text))
+			}
+			for i := m.Start; i < m.End; i++ {
+				masked[i] = true
+			}
+		}
+	}
+
+	for _, finding := range counts {
+		result.Findings = append(result.Findings, *finding)
+	}
+	sort.Slice(result.Findings, func(i, j int) bool {
+		a, b := result.Findings[i], result.Findings[j]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Term < b.Term
+	})
+
+	if masked != nil && result.Action != ActionReject {
+		runes := []rune(text)
+		for i, m := range masked {
+			if m {
+				runes[i] = f.mask
+			}
+		}
+		result.Text = string(runes)
+	}
+	return result
+}
+
+// covered 匹配是否完全落在某个白名单词的出现范围内
+func covered(m Match, allowed []Match) bool {
+	for _, a := range allowed {
+		if a.Start <= m.Start && m.End <= a.End {
+			return true
+		}
+	}
+	return false
+}
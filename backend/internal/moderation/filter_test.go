@@ -0,0 +1,170 @@
+package moderation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jugo/backend/config"
+)
+
+func newTestFilter(t *testing.T) *Filter {
+	t.Helper()
+	f, err := NewFilter(&config.AIModerationConfig{
+		Enabled: true,
+		Categories: map[string]config.AIModerationCategory{
+			"ads":      {Level: LevelLow, Words: []string{"加微信"}},
+			"abuse":    {Level: LevelMedium, Words: []string{"笨蛋"}},
+			"violence": {Level: LevelHigh, Words: []string{"杀手"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFilter() error = %v", err)
+	}
+	return f
+}
+
+func TestFilterCheck(t *testing.T) {
+	f := newTestFilter(t)
+	tests := []struct {
+		name     string
+		text     string
+		allowed  []string
+		want     Action
+		wantText string
+		findings []Finding
+	}{
+		{
+			name:     "no match",
+			text:     "今天天气很好",
+			want:     ActionNone,
+			wantText: "今天天气很好",
+		},
+		{
+			name:     "flag keeps text",
+			text:     "请加微信联系",
+			want:     ActionFlag,
+			wantText: "请加微信联系",
+			findings: []Finding{{Category: "ads", Level: LevelLow, Action: ActionFlag, Term: "加微信", Count: 1}},
+		},
+		{
+			name:     "mask replaces every occurrence",
+			text:     "你这个笨蛋笨蛋",
+			want:     ActionMask,
+			wantText: "你这个****",
+			findings: []Finding{{Category: "abuse", Level: LevelMedium, Action: ActionMask, Term: "笨蛋", Count: 2}},
+		},
+		{
+			name:     "reject wins and keeps original text",
+			text:     "他是杀手，笨蛋",
+			want:     ActionReject,
+			wantText: "他是杀手，笨蛋",
+			findings: []Finding{
+				{Category: "abuse", Level: LevelMedium, Action: ActionMask, Term: "笨蛋", Count: 1},
+				{Category: "violence", Level: LevelHigh, Action: ActionReject, Term: "杀手", Count: 1},
+			},
+		},
+		{
+			name:     "allowed term covers match",
+			text:     "这是他的杀手锏",
+			allowed:  []string{"杀手锏"},
+			want:     ActionNone,
+			wantText: "这是他的杀手锏",
+		},
+		{
+			name:     "allowed term only covers its own occurrence",
+			text:     "杀手锏和杀手",
+			allowed:  []string{"杀手锏"},
+			want:     ActionReject,
+			wantText: "杀手锏和杀手",
+			findings: []Finding{{Category: "violence", Level: LevelHigh, Action: ActionReject, Term: "杀手", Count: 1}},
+		},
+		{
+			name:     "allowed term does not cover partial overlap",
+			text:     "笨蛋蛋",
+			allowed:  []string{"蛋蛋"},
+			want:     ActionMask,
+			wantText: "**蛋",
+			findings: []Finding{{Category: "abuse", Level: LevelMedium, Action: ActionMask, Term: "笨蛋", Count: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.Check(tt.text, tt.allowed)
+			if got.Action != tt.want {
+				t.Errorf("Action = %q, want %q", got.Action, tt.want)
+			}
+			if got.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", got.Text, tt.wantText)
+			}
+			if !reflect.DeepEqual(got.Findings, tt.findings) {
+				t.Errorf("Findings = %+v, want %+v", got.Findings, tt.findings)
+			}
+		})
+	}
+}
+
+func TestNilFilterCheck(t *testing.T) {
+	var f *Filter
+	got := f.Check("杀手", nil)
+	if got.Action != ActionNone || got.Text != "杀手" {
+		t.Errorf("Check() on nil filter = %+v, want text unchanged", got)
+	}
+}
+
+func TestNewFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.AIModerationConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{
+			name:    "disabled",
+			cfg:     config.AIModerationConfig{Categories: map[string]config.AIModerationCategory{"a": {Level: LevelHigh}}},
+			wantNil: true,
+		},
+		{
+			name: "unknown level",
+			cfg: config.AIModerationConfig{
+				Enabled:    true,
+				Categories: map[string]config.AIModerationCategory{"a": {Level: "extreme", Words: []string{"x"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid category action",
+			cfg: config.AIModerationConfig{
+				Enabled:    true,
+				Categories: map[string]config.AIModerationCategory{"a": {Level: LevelLow, Action: "delete", Words: []string{"x"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid level action",
+			cfg: config.AIModerationConfig{
+				Enabled: true,
+				Levels:  map[string]string{LevelLow: "ignore"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "custom level",
+			cfg: config.AIModerationConfig{
+				Enabled:    true,
+				Levels:     map[string]string{"extreme": "reject"},
+				Categories: map[string]config.AIModerationCategory{"a": {Level: "extreme", Words: []string{"x"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (f == nil) != tt.wantNil {
+				t.Errorf("NewFilter() = %v, wantNil %v", f, tt.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jugo/backend/config"
+)
+
+// 模板文件路径格式：<模板名>/<变体>.<版本>.tmpl，如 continue/default.v1.tmpl、outline/genre-科幻.v2.tmpl
+const (
+	templateExt    = ".tmpl"
+	variantDefault = "default"
+	variantGenre   = "genre-"
+	variantType    = "worktype-"
+	// outputTemplate 模板中声明输出格式的子模板名，如 {{define "output"}}json{{end}}，未声明时输出为纯文本
+	outputTemplate = "output"
+)
+
+// OutputJSON 模板要求模型输出JSON
+const OutputJSON = "json"
+
+//go:embed templates
+var embedded embed.FS
+
+// Selector 模板选择条件
+type Selector struct {
+	WorkType string // 作品类型，匹配 worktype-<类型> 变体
+	Genre    string // 作品题材，匹配 genre-<题材> 变体，优先于作品类型
+	UserID   uint   // 用于实验分桶
+}
+
+// Rendered 渲染结果
+type Rendered struct {
+	Text    string
+	Version string // 模板标识，如 continue/default.v1
+	Output  string // 模板声明的输出格式，纯文本时为空
+}
+
+// version 同一变体的一个版本
+type version struct {
+	name   string // 版本号，如v1
+	num    int
+	tmpl   *template.Template
+	output string // 声明的输出格式
+}
+
+// templateSet 模板名 -> 变体 -> 版本列表（按版本号升序）
+type templateSet map[string]map[string][]*version
+
+// Store 提示词模板库
+type Store struct {
+	cfg *config.AIPromptConfig
+
+	mu       sync.RWMutex
+	set      templateSet
+	loadedAt time.Time
+}
+
+// NewStore 加载内置模板，配置了dir时用目录中的同名模板覆盖或补充
+func NewStore(cfg *config.AIPromptConfig) (*Store, error) {
+	s := &Store{cfg: cfg}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load 重新加载全部模板
+func (s *Store) load() error {
+	set := make(templateSet)
+
+	sub, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		return err
+	}
+	if err := set.loadFS(sub); err != nil {
+		return fmt.Errorf("failed to load embedded prompts: %w", err)
+	}
+	if s.cfg.Dir != "" {
+		if err := set.loadFS(os.DirFS(s.cfg.Dir)); err != nil {
+			return fmt.Errorf("failed to load prompts from %s: %w", s.cfg.Dir, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.set = set
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// loadFS 从文件系统加载模板，同名同版本的模板覆盖已有模板
+func (set templateSet) loadFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, templateExt) {
+			return nil
+		}
+
+		name, file := path.Split(p)
+		name = strings.TrimSuffix(name, "/")
+		variant, ver, ok := parseFileName(strings.TrimSuffix(file, templateExt))
+		if name == "" || !ok {
+			return fmt.Errorf("invalid prompt template path: %s", p)
+		}
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(p).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return err
+		}
+		output, err := declaredOutput(tmpl)
+		if err != nil {
+			return fmt.Errorf("invalid prompt template %s: %w", p, err)
+		}
+
+		if set[name] == nil {
+			set[name] = make(map[string][]*version)
+		}
+		set[name][variant] = insertVersion(set[name][variant], ver, tmpl, output)
+		return nil
+	})
+}
+
+// parseFileName 解析 <变体>.v<数字>
+func parseFileName(base string) (variant string, ver int, ok bool) {
+	i := strings.LastIndex(base, ".v")
+	if i <= 0 {
+		return "", 0, false
+	}
+	ver, err := strconv.Atoi(base[i+2:])
+	if err != nil || ver <= 0 {
+		return "", 0, false
+	}
+	return base[:i], ver, true
+}
+
+// declaredOutput 读取模板通过output子模板声明的输出格式
+func declaredOutput(tmpl *template.Template) (string, error) {
+	t := tmpl.Lookup(outputTemplate)
+	if t == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, nil); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// insertVersion 按版本号有序插入，相同版本号时替换
+func insertVersion(versions []*version, num int, tmpl *template.Template, output string) []*version {
+	v := &version{name: "v" + strconv.Itoa(num), num: num, tmpl: tmpl, output: output}
+	for i, existing := range versions {
+		if existing.num == num {
+			versions[i] = v
+			return versions
+		}
+		if existing.num > num {
+			return append(versions[:i], append([]*version{v}, versions[i:]...)...)
+		}
+	}
+	return append(versions, v)
+}
+
+// Render 选择模板并渲染：题材变体 > 作品类型变体 > default；
+// 版本优先级为命中的实验版本 > 配置固定的版本 > 最新版本
+func (s *Store) Render(name string, sel Selector, data interface{}) (*Rendered, error) {
+	s.reloadIfStale()
+
+	s.mu.RLock()
+	variants := s.set[name]
+	s.mu.RUnlock()
+	if variants == nil {
+		return nil, fmt.Errorf("prompt template not found: %s", name)
+	}
+
+	var candidates []string
+	if sel.Genre != "" {
+		candidates = append(candidates, variantGenre+sel.Genre)
+	}
+	if sel.WorkType != "" {
+		candidates = append(candidates, variantType+sel.WorkType)
+	}
+	candidates = append(candidates, variantDefault)
+
+	for _, variant := range candidates {
+		versions := variants[variant]
+		if len(versions) == 0 {
+			continue
+		}
+
+		v := s.pickVersion(name, versions, sel.UserID)
+		var buf bytes.Buffer
+		if err := v.tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render prompt %s: %w", name, err)
+		}
+		return &Rendered{
+			Text:    strings.TrimSpace(buf.String()),
+			Version: name + "/" + variant + "." + v.name,
+			Output:  v.output,
+		}, nil
+	}
+	return nil, fmt.Errorf("prompt template not found: %s", name)
+}
+
+// pickVersion 选择版本
+func (s *Store) pickVersion(name string, versions []*version, userID uint) *version {
+	if exp, ok := s.cfg.Experiments[name]; ok && exp.Percent > 0 && int(userID%100) < exp.Percent {
+		if v := findVersion(versions, exp.Version); v != nil {
+			return v
+		}
+	}
+	if pinned, ok := s.cfg.Versions[name]; ok {
+		if v := findVersion(versions, pinned); v != nil {
+			return v
+		}
+	}
+	return versions[len(versions)-1]
+}
+
+// findVersion 按版本号查找
+func findVersion(versions []*version, name string) *version {
+	for _, v := range versions {
+		if v.name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// reloadIfStale 从目录加载时按reload_interval重新读取模板，失败时继续使用已加载的模板
+func (s *Store) reloadIfStale() {
+	if s.cfg.Dir == "" || s.cfg.ReloadInterval <= 0 {
+		return
+	}
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) >= time.Duration(s.cfg.ReloadInterval)*time.Second
+	s.mu.RUnlock()
+	if !stale {
+		return
+	}
+	if err := s.load(); err != nil {
+		log.Printf("Failed to reload prompt templates: %v", err)
+		// 避免每次渲染都重试
+		s.mu.Lock()
+		s.loadedAt = time.Now()
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,177 @@
+package prompt
+
+import (
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/jugo/backend/config"
+)
+
+func TestParseFileName(t *testing.T) {
+	tests := []struct {
+		base        string
+		wantVariant string
+		wantVer     int
+		wantOK      bool
+	}{
+		{"default.v1", "default", 1, true},
+		{"genre-科幻.v12", "genre-科幻", 12, true},
+		{"worktype-screenplay.v2", "worktype-screenplay", 2, true},
+		{"default", "", 0, false},
+		{"default.v0", "", 0, false},
+		{"default.vx", "", 0, false},
+		{".v1", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.base, func(t *testing.T) {
+			variant, ver, ok := parseFileName(tt.base)
+			if variant != tt.wantVariant || ver != tt.wantVer || ok != tt.wantOK {
+				t.Errorf("parseFileName(%q) = (%q, %d, %v), want (%q, %d, %v)",
+					tt.base, variant, ver, ok, tt.wantVariant, tt.wantVer, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPickVersion(t *testing.T) {
+	// 乱序插入，验证按版本号排序
+	var versions []*version
+	for _, num := range []int{2, 1, 3} {
+		versions = insertVersion(versions, num, template.New(""), "")
+	}
+
+	tests := []struct {
+		name   string
+		cfg    config.AIPromptConfig
+		userID uint
+		want   string
+	}{
+		{
+			name: "latest by default",
+			want: "v3",
+		},
+		{
+			name: "pinned version",
+			cfg:  config.AIPromptConfig{Versions: map[string]string{"continue": "v1"}},
+			want: "v1",
+		},
+		{
+			name: "pinned version missing falls back to latest",
+			cfg:  config.AIPromptConfig{Versions: map[string]string{"continue": "v9"}},
+			want: "v3",
+		},
+		{
+			name: "pin for another template ignored",
+			cfg:  config.AIPromptConfig{Versions: map[string]string{"polish": "v1"}},
+			want: "v3",
+		},
+		{
+			name: "user in experiment bucket",
+			cfg: config.AIPromptConfig{
+				Versions:    map[string]string{"continue": "v1"},
+				Experiments: map[string]config.AIPromptExperiment{"continue": {Version: "v2", Percent: 50}},
+			},
+			userID: 149,
+			want:   "v2",
+		},
+		{
+			name: "user outside experiment bucket uses pinned version",
+			cfg: config.AIPromptConfig{
+				Versions:    map[string]string{"continue": "v1"},
+				Experiments: map[string]config.AIPromptExperiment{"continue": {Version: "v2", Percent: 50}},
+			},
+			userID: 150,
+			want:   "v1",
+		},
+		{
+			name: "experiment with zero percent",
+			cfg: config.AIPromptConfig{
+				Experiments: map[string]config.AIPromptExperiment{"continue": {Version: "v1", Percent: 0}},
+			},
+			userID: 0,
+			want:   "v3",
+		},
+		{
+			name: "experiment version missing falls back",
+			cfg: config.AIPromptConfig{
+				Experiments: map[string]config.AIPromptExperiment{"continue": {Version: "v9", Percent: 100}},
+			},
+			want: "v3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Store{cfg: &tt.cfg}
+			if got := s.pickVersion("continue", versions, tt.userID); got.name != tt.want {
+				t.Errorf("pickVersion() = %s, want %s", got.name, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	set := make(templateSet)
+	err := set.loadFS(fstest.MapFS{
+		"outline/default.v1.tmpl":             {Data: []byte("default v1 {{.}}")},
+		"outline/default.v2.tmpl":             {Data: []byte("{{define \"output\"}}json{{end}}\ndefault v2 {{.}}")},
+		"outline/genre-科幻.v1.tmpl":            {Data: []byte("genre v1 {{.}}")},
+		"outline/worktype-screenplay.v1.tmpl": {Data: []byte("worktype v1 {{.}}")},
+	})
+	if err != nil {
+		t.Fatalf("loadFS() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		versions    map[string]string
+		sel         Selector
+		wantText    string
+		wantVersion string
+		wantOutput  string
+	}{
+		{
+			name:        "genre variant first",
+			sel:         Selector{WorkType: "screenplay", Genre: "科幻"},
+			wantText:    "genre v1 x",
+			wantVersion: "outline/genre-科幻.v1",
+		},
+		{
+			name:        "work type variant",
+			sel:         Selector{WorkType: "screenplay", Genre: "都市"},
+			wantText:    "worktype v1 x",
+			wantVersion: "outline/worktype-screenplay.v1",
+		},
+		{
+			name:        "default variant latest version with declared output",
+			sel:         Selector{WorkType: "novel"},
+			wantText:    "default v2 x",
+			wantVersion: "outline/default.v2",
+			wantOutput:  OutputJSON,
+		},
+		{
+			name:        "pinned default version",
+			versions:    map[string]string{"outline": "v1"},
+			sel:         Selector{WorkType: "novel"},
+			wantText:    "default v1 x",
+			wantVersion: "outline/default.v1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Store{cfg: &config.AIPromptConfig{Versions: tt.versions}, set: set}
+			got, err := s.Render("outline", tt.sel, "x")
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got.Text != tt.wantText || got.Version != tt.wantVersion || got.Output != tt.wantOutput {
+				t.Errorf("Render() = %+v, want text %q, version %q, output %q", got, tt.wantText, tt.wantVersion, tt.wantOutput)
+			}
+		})
+	}
+
+	s := &Store{cfg: &config.AIPromptConfig{}, set: set}
+	if _, err := s.Render("missing", Selector{}, nil); err == nil {
+		t.Error("Render() of unknown template should fail")
+	}
+}
@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// eventChannelSuffix AI任务事件频道后缀
+const eventChannelSuffix = ":events"
+
+// EventReceiver AI任务事件接收方（如WebSocket推送器）
+type EventReceiver interface {
+	NotifyAITask(userID uint, event *dto.AITaskEvent)
+}
+
+// eventMessage 跨进程传递的AI任务事件
+type eventMessage struct {
+	UserID uint             `json:"userId"`
+	Event  *dto.AITaskEvent `json:"event"`
+}
+
+// RedisEventPublisher 将worker中产生的AI任务事件发布到Redis，实现service.AITaskNotifier接口
+type RedisEventPublisher struct {
+	rdb     *redis.Client
+	channel string
+	logger  *zap.Logger
+}
+
+// NewRedisEventPublisher 创建事件发布器
+func NewRedisEventPublisher(rdb *redis.Client, name string, logger *zap.Logger) *RedisEventPublisher {
+	if name == "" {
+		name = defaultQueueName
+	}
+	return &RedisEventPublisher{
+		rdb:     rdb,
+		channel: name + eventChannelSuffix,
+		logger:  logger,
+	}
+}
+
+// NotifyAITask 发布AI任务事件
+func (p *RedisEventPublisher) NotifyAITask(userID uint, event *dto.AITaskEvent) {
+	data, err := json.Marshal(&eventMessage{UserID: userID, Event: event})
+	if err != nil {
+		p.logger.Error("Failed to marshal AI task event", zap.Error(err))
+		return
+	}
+	if err := p.rdb.Publish(context.Background(), p.channel, data).Err(); err != nil {
+		p.logger.Error("Failed to publish AI task event", zap.Uint("taskId", event.TaskID), zap.Error(err))
+	}
+}
+
+// RelayEvents 订阅worker发布的AI任务事件并转交给receiver，阻塞直到ctx结束
+func RelayEvents(ctx context.Context, rdb *redis.Client, name string, receiver EventReceiver, logger *zap.Logger) error {
+	if name == "" {
+		name = defaultQueueName
+	}
+	pubsub := rdb.Subscribe(ctx, name+eventChannelSuffix)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe AI task events: %w", err)
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			var em eventMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &em); err != nil || em.Event == nil {
+				logger.Warn("Discarding malformed AI task event", zap.String("payload", msg.Payload))
+				continue
+			}
+			receiver.NotifyAITask(em.UserID, em.Event)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultMemoryQueueSize 内存队列默认容量
+const defaultMemoryQueueSize = 1024
+
+// memoryQueue 进程内任务队列（不持久化，仅用于本地开发和测试）
+type memoryQueue struct {
+	jobs   chan *Job
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewMemoryQueue 创建内存队列
+func NewMemoryQueue(size int) Queue {
+	if size <= 0 {
+		size = defaultMemoryQueueSize
+	}
+	return &memoryQueue{
+		jobs:   make(chan *Job, size),
+		closed: make(chan struct{}),
+	}
+}
+
+// Enqueue 将任务加入队列，队列已满时返回ErrQueueFull
+func (q *memoryQueue) Enqueue(ctx context.Context, job *Job) error {
+	select {
+	case <-q.closed:
+		return ErrQueueClosed
+	default:
+	}
+
+	select {
+	case q.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Dequeue 阻塞获取下一个任务
+func (q *memoryQueue) Dequeue(ctx context.Context) (*Delivery, error) {
+	select {
+	case job := <-q.jobs:
+		return &Delivery{Job: job}, nil
+	case <-q.closed:
+		return nil, ErrQueueClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close 关闭队列
+func (q *memoryQueue) Close() error {
+	q.once.Do(func() {
+		close(q.closed)
+	})
+	return nil
+}
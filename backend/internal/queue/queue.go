@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jugo/backend/config"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DriverRedis  = "redis"
+	DriverMemory = "memory"
+
+	defaultQueueName = "jugo:ai_tasks"
+)
+
+var (
+	ErrQueueClosed = errors.New("queue closed")
+	ErrQueueFull   = errors.New("queue full")
+)
+
+// Job 队列中的AI任务
+type Job struct {
+	TaskID     uint      `json:"taskId"`
+	Type       string    `json:"type"`
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+}
+
+// Delivery 一次出队投递，处理完成后必须调用Ack确认
+type Delivery struct {
+	Job *Job
+	ack func() error
+}
+
+// Ack 确认任务已处理完成，从队列中彻底移除
+func (d *Delivery) Ack() error {
+	if d.ack == nil {
+		return nil
+	}
+	return d.ack()
+}
+
+// Queue 任务队列接口
+type Queue interface {
+	// Enqueue 将任务加入队列
+	Enqueue(ctx context.Context, job *Job) error
+	// Dequeue 阻塞获取下一个任务，直到有任务或ctx结束
+	Dequeue(ctx context.Context) (*Delivery, error)
+	// Close 关闭队列
+	Close() error
+}
+
+// New 根据配置创建任务队列
+func New(cfg *config.QueueConfig, rdb *redis.Client) (Queue, error) {
+	name := cfg.Name
+	if name == "" {
+		name = defaultQueueName
+	}
+
+	switch cfg.Driver {
+	case DriverRedis, "":
+		if rdb == nil {
+			return nil, errors.New("redis queue requires a redis client")
+		}
+		return NewRedisQueue(rdb, name), nil
+	case DriverMemory:
+		return NewMemoryQueue(0), nil
+	default:
+		return nil, fmt.Errorf("unsupported queue driver: %s", cfg.Driver)
+	}
+}
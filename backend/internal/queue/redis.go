@@ -0,0 +1,196 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisBlockTimeout 单次阻塞出队的等待时间，超时后重新检查ctx
+	redisBlockTimeout = 5 * time.Second
+	// consumerHeartbeatInterval 消费者刷新存活标记的间隔
+	consumerHeartbeatInterval = 10 * time.Second
+	// consumerTTL 存活标记的有效期，超过该时长未刷新的消费者视为已退出
+	consumerTTL = 3 * consumerHeartbeatInterval
+	// reclaimInterval 回收已退出消费者处理中列表的间隔
+	reclaimInterval = time.Minute
+)
+
+// redisQueue 基于Redis List的持久化队列
+//
+// 出队时通过BLMOVE将任务原子地移入本消费者的处理中列表，任务处理完毕（已写入结束状态）Ack后才从处理中列表删除。
+// 消费者定期刷新带过期时间的存活标记，并把存活标记已过期的消费者的处理中列表移回队列，
+// 因此进程崩溃时未确认的任务会被其他消费者（或重启后的本进程）重新处理。
+type redisQueue struct {
+	rdb           *redis.Client
+	key           string
+	consumer      string
+	processingKey string
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stop      chan struct{}
+}
+
+// NewRedisQueue 创建Redis队列
+func NewRedisQueue(rdb *redis.Client, name string) Queue {
+	consumer := consumerID()
+	return &redisQueue{
+		rdb:           rdb,
+		key:           name,
+		consumer:      consumer,
+		processingKey: processingKey(name, consumer),
+		stop:          make(chan struct{}),
+	}
+}
+
+// processingKey 消费者的处理中列表
+func processingKey(name, consumer string) string {
+	return fmt.Sprintf("%s:processing:%s", name, consumer)
+}
+
+// consumerKey 消费者的存活标记
+func consumerKey(name, consumer string) string {
+	return fmt.Sprintf("%s:consumer:%s", name, consumer)
+}
+
+// Enqueue 将任务加入队列
+func (q *redisQueue) Enqueue(ctx context.Context, job *Job) error {
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now()
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	return q.rdb.LPush(ctx, q.key, data).Err()
+}
+
+// Dequeue 阻塞获取下一个任务，首次调用时开始刷新存活标记并回收已退出消费者的任务
+func (q *redisQueue) Dequeue(ctx context.Context) (*Delivery, error) {
+	q.startOnce.Do(q.startConsumer)
+
+	for {
+		raw, err := q.rdb.BLMove(ctx, q.key, q.processingKey, "RIGHT", "LEFT", redisBlockTimeout).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				// 等待超时，检查ctx后继续等待
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("failed to dequeue job: %w", err)
+		}
+
+		var job Job
+		if err := json.Unmarshal([]byte(raw), &job); err != nil {
+			// 无法解析的消息直接丢弃，避免阻塞队列
+			q.rdb.LRem(context.Background(), q.processingKey, 1, raw)
+			continue
+		}
+
+		return &Delivery{
+			Job: &job,
+			ack: func() error {
+				return q.rdb.LRem(context.Background(), q.processingKey, 1, raw).Err()
+			},
+		}, nil
+	}
+}
+
+// Close 停止刷新存活标记（Redis连接由调用方管理）
+func (q *redisQueue) Close() error {
+	q.stopOnce.Do(func() { close(q.stop) })
+	return nil
+}
+
+// startConsumer 写入存活标记，回收一次已退出消费者的任务，之后在后台定期执行
+func (q *redisQueue) startConsumer() {
+	ctx := context.Background()
+	q.heartbeat(ctx)
+	q.reclaim(ctx)
+
+	go func() {
+		heartbeat := time.NewTicker(consumerHeartbeatInterval)
+		defer heartbeat.Stop()
+		reclaim := time.NewTicker(reclaimInterval)
+		defer reclaim.Stop()
+		for {
+			select {
+			case <-q.stop:
+				return
+			case <-heartbeat.C:
+				q.heartbeat(ctx)
+			case <-reclaim.C:
+				q.reclaim(ctx)
+			}
+		}
+	}()
+}
+
+// heartbeat 刷新本消费者的存活标记
+func (q *redisQueue) heartbeat(ctx context.Context) {
+	if err := q.rdb.Set(ctx, consumerKey(q.key, q.consumer), time.Now().Unix(), consumerTTL).Err(); err != nil {
+		log.Printf("Failed to refresh queue consumer heartbeat: consumer=%s, err=%v", q.consumer, err)
+	}
+}
+
+// reclaim 将存活标记已过期的消费者处理中列表里的任务移回队列头部（最先出队）
+//
+// 重新投递的任务可能已经处理完毕，ProcessTask只认领等待中的任务，重复投递不会重复执行。
+func (q *redisQueue) reclaim(ctx context.Context) {
+	prefix := processingKey(q.key, "")
+	iter := q.rdb.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		consumer := strings.TrimPrefix(key, prefix)
+		if consumer == q.consumer {
+			continue
+		}
+		alive, err := q.rdb.Exists(ctx, consumerKey(q.key, consumer)).Result()
+		if err != nil || alive > 0 {
+			continue
+		}
+
+		moved := 0
+		for {
+			// 从最新的任务开始移到队列右端，最早的任务最终位于最右端，最先被取出
+			err := q.rdb.LMove(ctx, key, q.key, "LEFT", "RIGHT").Err()
+			if err != nil {
+				if !errors.Is(err, redis.Nil) {
+					log.Printf("Failed to reclaim queue jobs: key=%s, err=%v", key, err)
+				}
+				break
+			}
+			moved++
+		}
+		if moved > 0 {
+			log.Printf("Reclaimed %d jobs from stopped queue consumer %s", moved, consumer)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("Failed to scan queue processing lists: %v", err)
+	}
+}
+
+// consumerID 生成当前进程的消费者标识
+func consumerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
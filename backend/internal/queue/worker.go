@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// dequeueRetryDelay 出队失败后的重试间隔
+const dequeueRetryDelay = time.Second
+
+// Handler 任务处理函数，返回nil表示任务已终结（完成或失败均已写回），可以确认
+type Handler func(ctx context.Context, job *Job) error
+
+// Worker 并发消费队列中的任务
+type Worker struct {
+	queue       Queue
+	handler     Handler
+	concurrency int
+	logger      *zap.Logger
+}
+
+// NewWorker 创建Worker
+func NewWorker(queue Queue, handler Handler, concurrency int, logger *zap.Logger) *Worker {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Worker{
+		queue:       queue,
+		handler:     handler,
+		concurrency: concurrency,
+		logger:      logger,
+	}
+}
+
+// Run 启动消费协程并阻塞，直到ctx结束且所有进行中的任务处理完毕
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			w.loop(ctx, id)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// loop 单个消费协程的处理循环
+func (w *Worker) loop(ctx context.Context, id int) {
+	for {
+		delivery, err := w.queue.Dequeue(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrQueueClosed) {
+				return
+			}
+			w.logger.Error("Failed to dequeue job", zap.Int("worker", id), zap.Error(err))
+			select {
+			case <-time.After(dequeueRetryDelay):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		w.handle(delivery, id)
+	}
+}
+
+// handle 处理单个任务；使用独立的ctx，保证停机时进行中的任务能够完成并确认
+func (w *Worker) handle(delivery *Delivery, id int) {
+	job := delivery.Job
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Error("Job handler panicked",
+				zap.Int("worker", id),
+				zap.Uint("taskId", job.TaskID),
+				zap.String("panic", fmt.Sprint(r)),
+			)
+		}
+	}()
+
+	if err := w.handler(context.Background(), job); err != nil {
+		// 未确认的任务保留在处理中列表，由恢复流程处理
+		w.logger.Error("Failed to process job",
+			zap.Int("worker", id),
+			zap.Uint("taskId", job.TaskID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if err := delivery.Ack(); err != nil {
+		w.logger.Error("Failed to ack job", zap.Uint("taskId", job.TaskID), zap.Error(err))
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jugo/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAIApplicationNotFound = errors.New("AI application not found")
+)
+
+// AIApplicationRepository AI结果应用记录仓储接口
+type AIApplicationRepository interface {
+	Create(application *model.AIApplication) error
+	FindByID(id uint) (*model.AIApplication, error)
+	MarkUndone(id uint) (bool, error)
+}
+
+// aiApplicationRepository AI结果应用记录仓储实现
+type aiApplicationRepository struct {
+	db *gorm.DB
+}
+
+// NewAIApplicationRepository 创建AI结果应用记录仓储
+func NewAIApplicationRepository(db *gorm.DB) AIApplicationRepository {
+	return &aiApplicationRepository{db: db}
+}
+
+// Create 创建应用记录
+func (r *aiApplicationRepository) Create(application *model.AIApplication) error {
+	return r.db.Create(application).Error
+}
+
+// FindByID 根据ID查找应用记录
+func (r *aiApplicationRepository) FindByID(id uint) (*model.AIApplication, error) {
+	var application model.AIApplication
+	err := r.db.First(&application, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAIApplicationNotFound
+		}
+		return nil, err
+	}
+	return &application, nil
+}
+
+// MarkUndone 标记为已撤销，已撤销过时返回false
+func (r *aiApplicationRepository) MarkUndone(id uint) (bool, error) {
+	result := r.db.Model(&model.AIApplication{}).
+		Where("id = ? AND undone_at IS NULL", id).
+		Update("undone_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"time"
+
+	"github.com/jugo/backend/internal/dto"
 	"github.com/jugo/backend/internal/model"
 	"gorm.io/gorm"
 )
@@ -9,14 +12,35 @@ import (
 type AITaskRepository interface {
 	Create(task *model.AITask) error
 	FindByID(id uint) (*model.AITask, error)
-	FindByUserID(userID uint, limit, offset int) ([]*model.AITask, error)
+	FindByUserID(userID uint, params *dto.AITaskQueryParams, from, to time.Time) ([]*model.AITask, int, error)
 	Update(task *model.AITask) error
 	UpdateStatus(id uint, status model.AITaskStatus, progress int) error
+	Claim(id uint) (bool, error)
+	Touch(id uint) error
+	FindStale(taskType model.AITaskType, statuses []model.AITaskStatus, updatedBefore time.Time, limit int) ([]*model.AITask, error)
+	Requeue(id uint, statuses []model.AITaskStatus, updatedBefore time.Time) (bool, error)
+	FailStale(id uint, statuses []model.AITaskStatus, updatedBefore time.Time, errorMsg string) (bool, error)
 	UpdateResult(id uint, result string) error
-	UpdateError(id uint, errorMsg string) error
+	UpdatePartialResult(id uint, result string, progress int) error
+	Complete(task *model.AITask) (bool, error)
+	Fail(task *model.AITask, errorMsg string) (bool, error)
+	Reject(task *model.AITask, errorMsg string) (bool, error)
+	Cancel(id uint) (bool, error)
+	SaveCancelledUsage(task *model.AITask) error
+	GetStatus(id uint) (model.AITaskStatus, error)
+	SumUsage(userID uint, from, to time.Time, monthly bool) ([]dto.AIUsageItem, error)
 	CountByUserID(userID uint) (int64, error)
+	Delete(id uint) error
+	MarkMaterialized(id uint) (bool, error)
+	ResetMaterialized(id uint) error
 }
 
+// activeStatuses 未结束的任务状态
+var activeStatuses = []model.AITaskStatus{model.AITaskStatusPending, model.AITaskStatusProcessing}
+
+// terminalStatuses 已结束的任务状态，失败和取消的任务同样可能产生了费用
+var terminalStatuses = []model.AITaskStatus{model.AITaskStatusCompleted, model.AITaskStatusFailed, model.AITaskStatusCancelled}
+
 // aiTaskRepository AI任务仓储实现
 type aiTaskRepository struct {
 	db *gorm.DB
@@ -42,18 +66,53 @@ func (r *aiTaskRepository) FindByID(id uint) (*model.AITask, error) {
 	return &task, nil
 }
 
-// FindByUserID 根据用户ID查找AI任务列表
-func (r *aiTaskRepository) FindByUserID(userID uint, limit, offset int) ([]*model.AITask, error) {
+// FindByUserID 按条件分页查找用户的AI任务，from、to为零值时不限制创建时间
+func (r *aiTaskRepository) FindByUserID(userID uint, params *dto.AITaskQueryParams, from, to time.Time) ([]*model.AITask, int, error) {
 	var tasks []*model.AITask
-	err := r.db.Where("user_id = ?", userID).
+	var total int64
+
+	// 设置默认值
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.Limit == 0 {
+		params.Limit = 20
+	}
+
+	// 构建查询
+	query := r.db.Model(&model.AITask{}).Where("user_id = ?", userID)
+	if params.WorkID > 0 {
+		query = query.Where("work_id = ?", params.WorkID)
+	}
+	if params.Type != "" {
+		query = query.Where("type = ?", params.Type)
+	}
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("created_at < ?", to)
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询，列表中不需要任务参数
+	offset := (params.Page - 1) * params.Limit
+	err := query.Omit("parameters").
 		Order("created_at DESC").
-		Limit(limit).
+		Limit(params.Limit).
 		Offset(offset).
 		Find(&tasks).Error
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return tasks, nil
+	return tasks, int(total), nil
 }
 
 // Update 更新AI任务
@@ -61,16 +120,74 @@ func (r *aiTaskRepository) Update(task *model.AITask) error {
 	return r.db.Save(task).Error
 }
 
-// UpdateStatus 更新任务状态和进度
+// UpdateStatus 更新任务状态和进度（已结束的任务不再更新）
 func (r *aiTaskRepository) UpdateStatus(id uint, status model.AITaskStatus, progress int) error {
 	return r.db.Model(&model.AITask{}).
-		Where("id = ?", id).
+		Where("id = ? AND status IN ?", id, activeStatuses).
 		Updates(map[string]interface{}{
 			"status":   status,
 			"progress": progress,
 		}).Error
 }
 
+// Claim 将pending状态的任务认领为processing，返回是否认领成功
+func (r *aiTaskRepository) Claim(id uint) (bool, error) {
+	result := r.db.Model(&model.AITask{}).
+		Where("id = ? AND status = ?", id, model.AITaskStatusPending).
+		Updates(map[string]interface{}{
+			"status":   model.AITaskStatusProcessing,
+			"progress": 0,
+			"attempts": gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Touch 刷新执行中任务的更新时间（心跳）
+func (r *aiTaskRepository) Touch(id uint) error {
+	return r.db.Model(&model.AITask{}).
+		Where("id = ? AND status = ?", id, model.AITaskStatusProcessing).
+		Update("updated_at", time.Now()).Error
+}
+
+// FindStale 查找指定类型、指定状态中超过时限未更新的任务
+func (r *aiTaskRepository) FindStale(taskType model.AITaskType, statuses []model.AITaskStatus, updatedBefore time.Time, limit int) ([]*model.AITask, error) {
+	var tasks []*model.AITask
+	err := r.db.Where("type = ? AND status IN ? AND updated_at < ?", taskType, statuses, updatedBefore).
+		Order("updated_at ASC").
+		Limit(limit).
+		Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// Requeue 将中断的任务重置为pending，仅当期间没有新的心跳时才更新
+func (r *aiTaskRepository) Requeue(id uint, statuses []model.AITaskStatus, updatedBefore time.Time) (bool, error) {
+	res := r.db.Model(&model.AITask{}).
+		Where("id = ? AND status IN ? AND updated_at < ?", id, statuses, updatedBefore).
+		Updates(map[string]interface{}{
+			"status":   model.AITaskStatusPending,
+			"progress": 0,
+			"result":   "",
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// FailStale 将中断的任务标记为失败，仅当期间没有新的心跳时才更新
+func (r *aiTaskRepository) FailStale(id uint, statuses []model.AITaskStatus, updatedBefore time.Time, errorMsg string) (bool, error) {
+	res := r.db.Model(&model.AITask{}).
+		Where("id = ? AND status IN ? AND updated_at < ?", id, statuses, updatedBefore).
+		Updates(map[string]interface{}{
+			"status": model.AITaskStatusFailed,
+			"error":  errorMsg,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
 // UpdateResult 更新任务结果
 func (r *aiTaskRepository) UpdateResult(id uint, result string) error {
 	return r.db.Model(&model.AITask{}).
@@ -78,16 +195,124 @@ func (r *aiTaskRepository) UpdateResult(id uint, result string) error {
 		Update("result", result).Error
 }
 
-// UpdateError 更新任务错误信息
-func (r *aiTaskRepository) UpdateError(id uint, errorMsg string) error {
+// UpdatePartialResult 更新流式生成过程中的部分结果和进度
+func (r *aiTaskRepository) UpdatePartialResult(id uint, result string, progress int) error {
 	return r.db.Model(&model.AITask{}).
-		Where("id = ?", id).
+		Where("id = ? AND status = ?", id, model.AITaskStatusProcessing).
 		Updates(map[string]interface{}{
-			"status": model.AITaskStatusFailed,
-			"error":  errorMsg,
+			"result":   result,
+			"progress": progress,
 		}).Error
 }
 
+// Complete 保存任务结果并将处理中的任务标记为完成，返回是否更新成功（任务已被取消时返回false）
+func (r *aiTaskRepository) Complete(task *model.AITask) (bool, error) {
+	res := r.db.Model(&model.AITask{}).
+		Where("id = ? AND status = ?", task.ID, model.AITaskStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":         model.AITaskStatusCompleted,
+			"result":         task.Result,
+			"provider":       task.Provider,
+			"model":          task.Model,
+			"input_tokens":   task.InputTokens,
+			"output_tokens":  task.OutputTokens,
+			"cost":           task.Cost,
+			"cache_hit":      task.CacheHit,
+			"prompt_version": task.PromptVersion,
+			"moderation":     task.Moderation,
+			"progress":       100,
+			"completed_at":   task.CompletedAt,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Fail 将未结束的任务标记为失败并保存失败前已产生的用量，返回是否更新成功
+func (r *aiTaskRepository) Fail(task *model.AITask, errorMsg string) (bool, error) {
+	updates := usageUpdates(task)
+	updates["status"] = model.AITaskStatusFailed
+	updates["error"] = errorMsg
+
+	res := r.db.Model(&model.AITask{}).
+		Where("id = ? AND status IN ?", task.ID, activeStatuses).
+		Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Reject 将结果未通过审核的任务标记为失败：清除流式输出时写入的部分结果，记录审核发现和已产生的用量
+func (r *aiTaskRepository) Reject(task *model.AITask, errorMsg string) (bool, error) {
+	updates := usageUpdates(task)
+	updates["status"] = model.AITaskStatusFailed
+	updates["error"] = errorMsg
+	updates["result"] = ""
+	updates["moderation"] = task.Moderation
+
+	res := r.db.Model(&model.AITask{}).
+		Where("id = ? AND status IN ?", task.ID, activeStatuses).
+		Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Cancel 将未结束的任务标记为已取消，返回是否更新成功
+func (r *aiTaskRepository) Cancel(id uint) (bool, error) {
+	res := r.db.Model(&model.AITask{}).
+		Where("id = ? AND status IN ?", id, activeStatuses).
+		Updates(map[string]interface{}{
+			"status":       model.AITaskStatusCancelled,
+			"completed_at": time.Now(),
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// SaveCancelledUsage 保存已取消任务在取消前产生的用量和使用的提示词版本（取消由用户发起，执行任务的进程随后补写用量）
+func (r *aiTaskRepository) SaveCancelledUsage(task *model.AITask) error {
+	return r.db.Model(&model.AITask{}).
+		Where("id = ? AND status = ?", task.ID, model.AITaskStatusCancelled).
+		Updates(usageUpdates(task)).Error
+}
+
+// usageUpdates 任务用量相关的列，以及生成时使用的提示词版本（失败和被拒绝的任务同样需要用于对比实验）
+func usageUpdates(task *model.AITask) map[string]interface{} {
+	return map[string]interface{}{
+		"provider":       task.Provider,
+		"model":          task.Model,
+		"input_tokens":   task.InputTokens,
+		"output_tokens":  task.OutputTokens,
+		"cost":           task.Cost,
+		"cache_hit":      task.CacheHit,
+		"prompt_version": task.PromptVersion,
+	}
+}
+
+// GetStatus 获取任务当前状态
+func (r *aiTaskRepository) GetStatus(id uint) (model.AITaskStatus, error) {
+	var task model.AITask
+	err := r.db.Select("status").First(&task, id).Error
+	if err != nil {
+		return "", err
+	}
+	return task.Status, nil
+}
+
+// SumUsage 按周期和任务类型汇总用户已结束任务（完成、失败、取消）的用量，时间范围为[from, to)
+func (r *aiTaskRepository) SumUsage(userID uint, from, to time.Time, monthly bool) ([]dto.AIUsageItem, error) {
+	format := "%Y-%m-%d"
+	if monthly {
+		format = "%Y-%m"
+	}
+
+	// 已删除的任务同样产生了费用，需要计入
+	var items []dto.AIUsageItem
+	err := r.db.Unscoped().Model(&model.AITask{}).
+		Select("DATE_FORMAT(created_at, ?) AS period, type AS task_type, COUNT(*) AS tasks, "+
+			"SUM(input_tokens) AS input_tokens, SUM(output_tokens) AS output_tokens, SUM(cost) AS cost", format).
+		Where("user_id = ? AND status IN ? AND created_at >= ? AND created_at < ?",
+			userID, terminalStatuses, from, to).
+		Group("period, task_type").
+		Order("period, task_type").
+		Scan(&items).Error
+	return items, err
+}
+
 // CountByUserID 统计用户的AI任务数量
 func (r *aiTaskRepository) CountByUserID(userID uint) (int64, error) {
 	var count int64
@@ -96,3 +321,23 @@ func (r *aiTaskRepository) CountByUserID(userID uint) (int64, error) {
 		Count(&count).Error
 	return count, err
 }
+
+// Delete 删除AI任务（软删除，用量统计仍包含已删除的任务）
+func (r *aiTaskRepository) Delete(id uint) error {
+	return r.db.Delete(&model.AITask{}, id).Error
+}
+
+// MarkMaterialized 标记大纲任务已生成章节，返回是否标记成功（已标记过时返回false）
+func (r *aiTaskRepository) MarkMaterialized(id uint) (bool, error) {
+	res := r.db.Model(&model.AITask{}).
+		Where("id = ? AND materialized_at IS NULL", id).
+		Update("materialized_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+// ResetMaterialized 清除生成章节标记（生成章节失败时回滚）
+func (r *aiTaskRepository) ResetMaterialized(id uint) error {
+	return r.db.Model(&model.AITask{}).
+		Where("id = ?", id).
+		Update("materialized_at", nil).Error
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jugo/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAITaskCandidateNotFound = errors.New("AI task candidate not found")
+)
+
+// AITaskCandidateRepository AI任务候选结果仓储接口
+type AITaskCandidateRepository interface {
+	ReplaceByTaskID(taskID uint, candidates []*model.AITaskCandidate) error
+	FindByID(id uint) (*model.AITaskCandidate, error)
+	FindByTaskID(taskID uint) ([]model.AITaskCandidate, error)
+	Choose(candidate *model.AITaskCandidate) error
+}
+
+// aiTaskCandidateRepository AI任务候选结果仓储实现
+type aiTaskCandidateRepository struct {
+	db *gorm.DB
+}
+
+// NewAITaskCandidateRepository 创建AI任务候选结果仓储
+func NewAITaskCandidateRepository(db *gorm.DB) AITaskCandidateRepository {
+	return &aiTaskCandidateRepository{db: db}
+}
+
+// ReplaceByTaskID 保存任务的候选结果，替换之前保存的候选（任务被重新执行时序号相同的候选已存在）
+func (r *aiTaskCandidateRepository) ReplaceByTaskID(taskID uint, candidates []*model.AITaskCandidate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", taskID).Delete(&model.AITaskCandidate{}).Error; err != nil {
+			return err
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+		return tx.Create(candidates).Error
+	})
+}
+
+// FindByID 根据ID查找候选结果
+func (r *aiTaskCandidateRepository) FindByID(id uint) (*model.AITaskCandidate, error) {
+	var candidate model.AITaskCandidate
+	err := r.db.First(&candidate, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAITaskCandidateNotFound
+		}
+		return nil, err
+	}
+	return &candidate, nil
+}
+
+// FindByTaskID 按序号查找任务的全部候选结果
+func (r *aiTaskCandidateRepository) FindByTaskID(taskID uint) ([]model.AITaskCandidate, error) {
+	var candidates []model.AITaskCandidate
+	err := r.db.Where("task_id = ?", taskID).Order("candidate_index ASC").Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// Choose 将候选标记为选中（同一任务只有一个选中的候选），并把其内容写入任务结果
+func (r *aiTaskCandidateRepository) Choose(candidate *model.AITaskCandidate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.AITaskCandidate{}).
+			Where("task_id = ?", candidate.TaskID).
+			Update("chosen", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.AITaskCandidate{}).
+			Where("id = ?", candidate.ID).
+			Update("chosen", true).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.AITask{}).
+			Where("id = ?", candidate.TaskID).
+			Update("result", candidate.Text).Error
+	})
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"github.com/jugo/backend/internal/model"
 	"gorm.io/gorm"
@@ -16,10 +17,17 @@ type ChapterRepository interface {
 	Create(chapter *model.Chapter) error
 	FindByID(id uint) (*model.Chapter, error)
 	FindByWorkID(workID uint) ([]model.Chapter, error)
+	FindPrevious(workID uint, orderNum int) (*model.Chapter, error)
 	Update(chapter *model.Chapter) error
 	Delete(id uint) error
 	CountByWorkID(workID uint) (int, error)
 	GetTotalWordsByWorkID(workID uint) (int, error)
+	FindSummaryDue(before time.Time, limit int) ([]model.Chapter, error)
+	ClaimSummaryDue(id uint, dueAt time.Time) (bool, error)
+	UpdateSummary(id uint, summary string, consumedChanges int) error
+	AddSummaryChanges(id uint, changed, threshold int, dueAt time.Time) error
+	FindSummaries(workID uint) ([]model.Chapter, error)
+	FindRecentSummaries(workID uint, beforeOrder, limit int) ([]model.Chapter, error)
 }
 
 // chapterRepository 章节仓储实现
@@ -60,9 +68,24 @@ func (r *chapterRepository) FindByWorkID(workID uint) ([]model.Chapter, error) {
 	return chapters, nil
 }
 
-// Update 更新章节
+// FindPrevious 查找作品中排在orderNum之前的最后一章
+func (r *chapterRepository) FindPrevious(workID uint, orderNum int) (*model.Chapter, error) {
+	var chapter model.Chapter
+	err := r.db.Where("work_id = ? AND order_num < ?", workID, orderNum).
+		Order("order_num DESC").
+		First(&chapter).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChapterNotFound
+		}
+		return nil, err
+	}
+	return &chapter, nil
+}
+
+// Update 更新章节（摘要及其计划由AddSummaryChanges和UpdateSummary单独更新，这里不覆盖）
 func (r *chapterRepository) Update(chapter *model.Chapter) error {
-	return r.db.Save(chapter).Error
+	return r.db.Omit("summary", "summarized_at", "summary_changes", "summary_due_at").Save(chapter).Error
 }
 
 // Delete 删除章节
@@ -86,3 +109,75 @@ func (r *chapterRepository) GetTotalWordsByWorkID(workID uint) (int, error) {
 		Scan(&totalWords).Error
 	return totalWords, err
 }
+
+// FindSummaryDue 查找到期需要重新生成摘要的章节（只加载ID、作品ID和计划时间）
+func (r *chapterRepository) FindSummaryDue(before time.Time, limit int) ([]model.Chapter, error) {
+	var chapters []model.Chapter
+	err := r.db.Select("id", "work_id", "summary_due_at").
+		Where("summary_due_at IS NOT NULL AND summary_due_at <= ?", before).
+		Order("summary_due_at ASC").
+		Limit(limit).
+		Find(&chapters).Error
+	return chapters, err
+}
+
+// ClaimSummaryDue 清除计划时间以认领摘要生成，计划时间已被推迟或已被认领时返回false
+func (r *chapterRepository) ClaimSummaryDue(id uint, dueAt time.Time) (bool, error) {
+	res := r.db.Model(&model.Chapter{}).
+		Where("id = ? AND summary_due_at = ?", id, dueAt).
+		UpdateColumn("summary_due_at", nil)
+	return res.RowsAffected > 0, res.Error
+}
+
+// UpdateSummary 更新章节摘要，并扣除生成摘要时已计入的变化字数（生成期间的新变化保留）
+func (r *chapterRepository) UpdateSummary(id uint, summary string, consumedChanges int) error {
+	return r.db.Model(&model.Chapter{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"summary":         summary,
+			"summarized_at":   time.Now(),
+			"summary_changes": gorm.Expr("GREATEST(summary_changes - ?, 0)", consumedChanges),
+		}).Error
+}
+
+// AddSummaryChanges 累计章节变化的字数，累计值达到threshold时把摘要的生成时间推迟到dueAt
+//
+// 直接在数据库中累加并按累加后的值条件更新，并发保存和摘要任务消费变化量时不会相互覆盖。
+func (r *chapterRepository) AddSummaryChanges(id uint, changed, threshold int, dueAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Chapter{}).Where("id = ?", id).
+			UpdateColumn("summary_changes", gorm.Expr("summary_changes + ?", changed)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Chapter{}).
+			Where("id = ? AND summary_changes >= ?", id, threshold).
+			UpdateColumn("summary_due_at", dueAt).Error
+	})
+}
+
+// FindSummaries 按顺序查找作品各章的标题和摘要（不加载正文）
+func (r *chapterRepository) FindSummaries(workID uint) ([]model.Chapter, error) {
+	var chapters []model.Chapter
+	err := r.db.Select("id", "work_id", "title", "order_num", "summary", "summarized_at").
+		Where("work_id = ?", workID).
+		Order("order_num ASC").
+		Find(&chapters).Error
+	return chapters, err
+}
+
+// FindRecentSummaries 按顺序查找排在beforeOrder之前（为0时不限制）的最后limit个有内容或摘要的章节，只读取标题和摘要，不读取正文
+func (r *chapterRepository) FindRecentSummaries(workID uint, beforeOrder, limit int) ([]model.Chapter, error) {
+	query := r.db.Select("id", "work_id", "title", "order_num", "words", "summary").
+		Where("work_id = ? AND (words > 0 OR summary <> '')", workID)
+	if beforeOrder > 0 {
+		query = query.Where("order_num < ?", beforeOrder)
+	}
+
+	var chapters []model.Chapter
+	if err := query.Order("order_num DESC").Limit(limit).Find(&chapters).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(chapters)-1; i < j; i, j = i+1, j-1 {
+		chapters[i], chapters[j] = chapters[j], chapters[i]
+	}
+	return chapters, nil
+}
@@ -15,11 +15,15 @@ var (
 // WorkRepository 作品仓储接口
 type WorkRepository interface {
 	Create(work *model.Work) error
+	CreateWithContent(work *model.Work, chapters []*model.Chapter, characters []*model.Character) error
+	AddChapters(workID uint, chapters []*model.Chapter) (int, error)
 	FindByID(id uint) (*model.Work, error)
 	FindByUserID(userID uint, params *dto.WorkQueryParams) ([]model.Work, int, error)
 	Update(work *model.Work) error
 	Delete(id uint) error
 	UpdateStatistics(workID uint, words, numChapters int) error
+	UpdateMetadata(workID uint, metadata model.WorkMetadata) error
+	UpdateSynopsis(workID uint, synopsis string) error
 }
 
 // workRepository 作品仓储实现
@@ -37,6 +41,59 @@ func (r *workRepository) Create(work *model.Work) error {
 	return r.db.Create(work).Error
 }
 
+// CreateWithContent 在一个事务中创建作品及其章节和角色，任一步失败时全部回滚
+func (r *workRepository) CreateWithContent(work *model.Work, chapters []*model.Chapter, characters []*model.Character) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(work).Error; err != nil {
+			return err
+		}
+		for _, chapter := range chapters {
+			chapter.WorkID = work.ID
+			if err := tx.Create(chapter).Error; err != nil {
+				return err
+			}
+		}
+		for _, character := range characters {
+			character.WorkID = work.ID
+			if err := tx.Create(character).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddChapters 在一个事务中为作品追加章节并更新作品统计，返回追加后的章节数，任一步失败时全部回滚
+func (r *workRepository) AddChapters(workID uint, chapters []*model.Chapter) (int, error) {
+	var count int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, chapter := range chapters {
+			chapter.WorkID = workID
+			if err := tx.Create(chapter).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&model.Chapter{}).Where("work_id = ?", workID).Count(&count).Error; err != nil {
+			return err
+		}
+		var totalWords int
+		err := tx.Model(&model.Chapter{}).
+			Where("work_id = ?", workID).
+			Select("COALESCE(SUM(words), 0)").
+			Scan(&totalWords).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.Work{}).Where("id = ?", workID).
+			Updates(map[string]interface{}{
+				"words":        totalWords,
+				"num_chapters": count,
+			}).Error
+	})
+	return int(count), err
+}
+
 // FindByID 根据ID查找作品
 func (r *workRepository) FindByID(id uint) (*model.Work, error) {
 	var work model.Work
@@ -79,6 +136,11 @@ func (r *workRepository) FindByUserID(userID uint, params *dto.WorkQueryParams)
 		query = query.Where("status = ?", params.Status)
 	}
 
+	// 改编来源过滤
+	if params.SourceWorkID > 0 {
+		query = query.Where("source_work_id = ?", params.SourceWorkID)
+	}
+
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -107,9 +169,9 @@ func (r *workRepository) FindByUserID(userID uint, params *dto.WorkQueryParams)
 	return works, int(total), nil
 }
 
-// Update 更新作品
+// Update 更新作品（全书梗概由后台任务通过UpdateSynopsis单独更新，这里不覆盖）
 func (r *workRepository) Update(work *model.Work) error {
-	return r.db.Save(work).Error
+	return r.db.Omit("synopsis").Save(work).Error
 }
 
 // Delete 删除作品
@@ -125,3 +187,15 @@ func (r *workRepository) UpdateStatistics(workID uint, words, numChapters int) e
 			"num_chapters": numChapters,
 		}).Error
 }
+
+// UpdateMetadata 只更新作品元数据
+func (r *workRepository) UpdateMetadata(workID uint, metadata model.WorkMetadata) error {
+	return r.db.Model(&model.Work{}).Where("id = ?", workID).
+		Update("metadata", metadata).Error
+}
+
+// UpdateSynopsis 只更新全书梗概
+func (r *workRepository) UpdateSynopsis(workID uint, synopsis string) error {
+	return r.db.Model(&model.Work{}).Where("id = ?", workID).
+		Update("synopsis", synopsis).Error
+}
@@ -5,17 +5,44 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jugo/backend/config"
 	"github.com/jugo/backend/internal/dto"
 	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/internal/moderation"
+	"github.com/jugo/backend/internal/prompt"
+	"github.com/jugo/backend/internal/queue"
 	"github.com/jugo/backend/internal/repository"
 	"github.com/jugo/backend/pkg/ai"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 var (
 	ErrAITaskNotFound = errors.New("AI task not found")
+	ErrEnqueueAITask  = errors.New("failed to enqueue AI task")
+
+	ErrAITaskNotCancellable = errors.New("AI task already finished")
+)
+
+const (
+	// streamFlushInterval 流式生成时部分结果写入数据库的最小间隔
+	streamFlushInterval = time.Second
+	// streamPushInterval 流式生成时向客户端推送文本片段的最小间隔
+	streamPushInterval = 100 * time.Millisecond
+	// cancelPollInterval 执行中检查任务是否被取消的间隔（取消请求可能来自其他进程）
+	cancelPollInterval = 2 * time.Second
+	// heartbeatInterval 执行中刷新任务更新时间的间隔，供恢复流程判断任务是否中断
+	heartbeatInterval = 30 * time.Second
+	// statusWriteAttempts 写入任务结束状态的最多尝试次数
+	statusWriteAttempts = 3
+	// statusWriteBackoff 写入失败后的等待时间，按尝试次数递增
+	statusWriteBackoff = 500 * time.Millisecond
 )
 
 // AIService AI服务接口
@@ -27,30 +54,94 @@ type AIService interface {
 	GenerateOutline(userID uint, req *dto.OutlineRequest) (*dto.AITaskResponse, error)
 	ConvertNovelToScreenplay(userID uint, req *dto.NovelToScreenplayRequest) (*dto.AITaskResponse, error)
 	ConvertScreenplayToNovel(userID uint, req *dto.ScreenplayToNovelRequest) (*dto.AITaskResponse, error)
+	CheckConsistency(userID uint, req *dto.ConsistencyCheckRequest) (*dto.AITaskResponse, error)
+	ExpandSnowflake(userID uint, req *dto.SnowflakeExpandRequest) (*dto.AITaskResponse, error)
+	GenerateCharacters(userID uint, req *dto.CharacterGenerateRequest) (*dto.AITaskResponse, error)
 	GetTaskStatus(userID, taskID uint) (*dto.TaskStatusResponse, error)
+	CancelTask(userID, taskID uint) (*dto.TaskStatusResponse, error)
+	ChooseCandidate(userID, taskID, candidateID uint) (*dto.TaskStatusResponse, error)
+	ListTasks(userID uint, params *dto.AITaskQueryParams) (*dto.AITaskListResponse, error)
+	DeleteTask(userID, taskID uint) error
+	ApplyTask(userID, taskID uint, req *dto.AIApplyRequest) (*dto.AIApplyResponse, error)
+	UndoApplication(userID, applicationID uint) (*dto.AIApplyResponse, error)
+	MaterializeOutline(userID, taskID uint, req *dto.MaterializeOutlineRequest) (*dto.MaterializeOutlineResponse, error)
+	GetUsage(userID uint, query *dto.AIUsageQuery) (*dto.AIUsageResponse, error)
+	// ProcessTask 执行队列中的AI任务，由worker调用
+	ProcessTask(ctx context.Context, taskID uint) error
+	// RunRecovery 启动时及之后定期恢复中断的任务，阻塞直到ctx结束
+	RunRecovery(ctx context.Context)
+	// RunSummaryScheduler 定期为到期的章节创建摘要任务，阻塞直到ctx结束
+	RunSummaryScheduler(ctx context.Context)
 }
 
 // aiService AI服务实现
 type aiService struct {
-	aiTaskRepo     repository.AITaskRepository
-	workRepo       repository.WorkRepository
-	claudeClient   ai.Client
-	deepSeekClient ai.Client
-	cfg            *config.Config
+	aiTaskRepo       repository.AITaskRepository
+	workRepo         repository.WorkRepository
+	chapterRepo      repository.ChapterRepository
+	characterRepo    repository.CharacterRepository
+	applicationRepo  repository.AIApplicationRepository
+	candidateRepo    repository.AITaskCandidateRepository
+	saveService      SaveService
+	characterService CharacterService
+	providers        *ai.Registry
+	clients          map[model.AITaskType]ai.Client // 按任务类型组装的提供商链路
+	taskQueue        queue.Queue
+	notifier         AITaskNotifier
+	prompts          *prompt.Store
+	quota            *aiQuota
+	cache            *aiCache
+	moderator        *moderation.Filter
+	cfg              *config.Config
+
+	// 本进程中正在执行的任务，用于立即取消
+	runningMu sync.Mutex
+	running   map[uint]context.CancelFunc
+
+	startedAt time.Time
 }
 
 // NewAIService 创建AI服务
 func NewAIService(
 	aiTaskRepo repository.AITaskRepository,
 	workRepo repository.WorkRepository,
+	chapterRepo repository.ChapterRepository,
+	characterRepo repository.CharacterRepository,
+	applicationRepo repository.AIApplicationRepository,
+	candidateRepo repository.AITaskCandidateRepository,
+	saveService SaveService,
+	characterService CharacterService,
+	taskQueue queue.Queue,
+	notifier AITaskNotifier,
+	providers *ai.Registry,
+	prompts *prompt.Store,
+	moderator *moderation.Filter,
+	rdb *redis.Client,
 	cfg *config.Config,
 ) AIService {
+	if notifier == nil {
+		notifier = noopNotifier{}
+	}
 	return &aiService{
-		aiTaskRepo:     aiTaskRepo,
-		workRepo:       workRepo,
-		claudeClient:   ai.NewClient(ai.ProviderClaude, &cfg.AI.Claude),
-		deepSeekClient: ai.NewClient(ai.ProviderDeepSeek, &cfg.AI.DeepSeek),
-		cfg:            cfg,
+		aiTaskRepo:       aiTaskRepo,
+		workRepo:         workRepo,
+		chapterRepo:      chapterRepo,
+		characterRepo:    characterRepo,
+		applicationRepo:  applicationRepo,
+		candidateRepo:    candidateRepo,
+		saveService:      saveService,
+		characterService: characterService,
+		providers:        providers,
+		clients:          newTaskClients(providers, &cfg.AI),
+		taskQueue:        taskQueue,
+		notifier:         notifier,
+		prompts:          prompts,
+		quota:            newAIQuota(rdb, &cfg.AI.Quota),
+		cache:            newAICache(rdb, &cfg.AI.Cache),
+		moderator:        moderator,
+		cfg:              cfg,
+		running:          make(map[uint]context.CancelFunc),
+		startedAt:        time.Now(),
 	}
 }
 
@@ -60,30 +151,16 @@ func (s *aiService) Continue(userID uint, req *dto.ContinueRequest) (*dto.AITask
 	if err := s.validateWorkOwnership(userID, req.WorkID); err != nil {
 		return nil, err
 	}
-
-	// 创建任务记录
-	params, _ := json.Marshal(req)
-	task := &model.AITask{
-		UserID:     userID,
-		WorkID:     req.WorkID,
-		Type:       model.AITaskTypeContinue,
-		Status:     model.AITaskStatusPending,
-		Parameters: string(params),
-		Progress:   0,
+	if err := s.validateContinueChapter(req); err != nil {
+		return nil, err
 	}
 
-	if err := s.aiTaskRepo.Create(task); err != nil {
+	if err := s.validateCandidates(req.AICandidateOptions); err != nil {
 		return nil, err
 	}
 
-	// 异步处理任务
-	go s.processContinueTask(task.ID, req)
-
-	return &dto.AITaskResponse{
-		TaskID:        task.ID,
-		Status:        string(task.Status),
-		EstimatedTime: 30,
-	}, nil
+	// 创建任务并加入队列
+	return s.createTask(userID, req.WorkID, model.AITaskTypeContinue, req, 30)
 }
 
 // Polish AI润色
@@ -93,29 +170,12 @@ func (s *aiService) Polish(userID uint, req *dto.PolishRequest) (*dto.AITaskResp
 		return nil, err
 	}
 
-	// 创建任务记录
-	params, _ := json.Marshal(req)
-	task := &model.AITask{
-		UserID:     userID,
-		WorkID:     req.WorkID,
-		Type:       model.AITaskTypePolish,
-		Status:     model.AITaskStatusPending,
-		Parameters: string(params),
-		Progress:   0,
-	}
-
-	if err := s.aiTaskRepo.Create(task); err != nil {
+	if err := s.validateCandidates(req.AICandidateOptions); err != nil {
 		return nil, err
 	}
 
-	// 异步处理任务
-	go s.processPolishTask(task.ID, req)
-
-	return &dto.AITaskResponse{
-		TaskID:        task.ID,
-		Status:        string(task.Status),
-		EstimatedTime: 20,
-	}, nil
+	// 创建任务并加入队列
+	return s.createTask(userID, req.WorkID, model.AITaskTypePolish, req, 20)
 }
 
 // Expand AI扩写
@@ -125,29 +185,12 @@ func (s *aiService) Expand(userID uint, req *dto.ExpandRequest) (*dto.AITaskResp
 		return nil, err
 	}
 
-	// 创建任务记录
-	params, _ := json.Marshal(req)
-	task := &model.AITask{
-		UserID:     userID,
-		WorkID:     req.WorkID,
-		Type:       model.AITaskTypeExpand,
-		Status:     model.AITaskStatusPending,
-		Parameters: string(params),
-		Progress:   0,
-	}
-
-	if err := s.aiTaskRepo.Create(task); err != nil {
+	if err := s.validateCandidates(req.AICandidateOptions); err != nil {
 		return nil, err
 	}
 
-	// 异步处理任务
-	go s.processExpandTask(task.ID, req)
-
-	return &dto.AITaskResponse{
-		TaskID:        task.ID,
-		Status:        string(task.Status),
-		EstimatedTime: 40,
-	}, nil
+	// 创建任务并加入队列
+	return s.createTask(userID, req.WorkID, model.AITaskTypeExpand, req, 40)
 }
 
 // Rewrite AI改写
@@ -157,33 +200,66 @@ func (s *aiService) Rewrite(userID uint, req *dto.RewriteRequest) (*dto.AITaskRe
 		return nil, err
 	}
 
-	// 创建任务记录
-	params, _ := json.Marshal(req)
-	task := &model.AITask{
-		UserID:     userID,
-		WorkID:     req.WorkID,
-		Type:       model.AITaskTypeRewrite,
-		Status:     model.AITaskStatusPending,
-		Parameters: string(params),
-		Progress:   0,
+	if err := s.validateCandidates(req.AICandidateOptions); err != nil {
+		return nil, err
 	}
 
-	if err := s.aiTaskRepo.Create(task); err != nil {
+	// 创建任务并加入队列
+	return s.createTask(userID, req.WorkID, model.AITaskTypeRewrite, req, 25)
+}
+
+// GetTaskStatus 获取任务状态
+func (s *aiService) GetTaskStatus(userID, taskID uint) (*dto.TaskStatusResponse, error) {
+	task, err := s.findOwnedTask(userID, taskID)
+	if err != nil {
 		return nil, err
 	}
 
-	// 异步处理任务
-	go s.processRewriteTask(task.ID, req)
+	resp := s.toTaskStatusResponse(task)
+	candidates, err := s.candidateRepo.FindByTaskID(task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) > 0 {
+		resp.Candidates = toCandidates(candidates)
+	}
+	return resp, nil
+}
 
-	return &dto.AITaskResponse{
-		TaskID:        task.ID,
-		Status:        string(task.Status),
-		EstimatedTime: 25,
-	}, nil
+// CancelTask 取消等待中或执行中的任务
+func (s *aiService) CancelTask(userID, taskID uint) (*dto.TaskStatusResponse, error) {
+	task, err := s.findOwnedTask(userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.aiTaskRepo.Cancel(task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, ErrAITaskNotCancellable
+	}
+
+	// 本进程中执行的任务立即中断，其他worker中的任务通过状态轮询感知
+	s.runningMu.Lock()
+	if cancel, ok := s.running[task.ID]; ok {
+		cancel()
+	}
+	s.runningMu.Unlock()
+
+	task, err = s.aiTaskRepo.FindByID(task.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.quota.Release(context.Background(), task)
+	s.notify(task, &dto.AITaskEvent{Event: AITaskEventCancelled})
+
+	return s.toTaskStatusResponse(task), nil
 }
 
-// GetTaskStatus 获取任务状态
-func (s *aiService) GetTaskStatus(userID, taskID uint) (*dto.TaskStatusResponse, error) {
+// findOwnedTask 查找任务并验证所有权
+func (s *aiService) findOwnedTask(userID, taskID uint) (*model.AITask, error) {
 	task, err := s.aiTaskRepo.FindByID(taskID)
 	if err != nil {
 		return nil, ErrAITaskNotFound
@@ -193,16 +269,32 @@ func (s *aiService) GetTaskStatus(userID, taskID uint) (*dto.TaskStatusResponse,
 	if task.UserID != userID {
 		return nil, ErrUnauthorized
 	}
+	return task, nil
+}
 
-	return &dto.TaskStatusResponse{
+// toTaskStatusResponse 转换为任务状态响应
+func (s *aiService) toTaskStatusResponse(task *model.AITask) *dto.TaskStatusResponse {
+	resp := &dto.TaskStatusResponse{
 		TaskID:      task.ID,
 		Status:      string(task.Status),
 		Progress:    task.Progress,
+		Provider:    task.Provider,
+		Model:       task.Model,
+		CacheHit:    task.CacheHit,
+		Moderation:  task.Moderation,
 		Result:      task.Result,
 		Error:       task.Error,
 		CreatedAt:   task.CreatedAt,
 		CompletedAt: task.CompletedAt,
-	}, nil
+	}
+	if task.InputTokens > 0 || task.OutputTokens > 0 {
+		resp.Usage = &dto.AIUsage{
+			InputTokens:  task.InputTokens,
+			OutputTokens: task.OutputTokens,
+			Cost:         task.Cost,
+		}
+	}
+	return resp
 }
 
 // validateWorkOwnership 验证作品所有权
@@ -217,224 +309,425 @@ func (s *aiService) validateWorkOwnership(userID, workID uint) error {
 	return nil
 }
 
-// processContinueTask 处理续写任务
-func (s *aiService) processContinueTask(taskID uint, req *dto.ContinueRequest) {
-	ctx := context.Background()
+// createTask 创建任务记录并加入队列，由worker异步处理
+func (s *aiService) createTask(userID, workID uint, taskType model.AITaskType, req interface{}, estimatedTime int) (*dto.AITaskResponse, error) {
+	slot, err := s.quota.Check(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
 
-	// 更新任务状态为处理中
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 10)
+	params, _ := json.Marshal(req)
+	task := &model.AITask{
+		UserID:     userID,
+		WorkID:     workID,
+		Type:       taskType,
+		Status:     model.AITaskStatusPending,
+		Parameters: string(params),
+		Progress:   0,
+	}
 
-	// 构建提示词
-	prompt := s.buildContinuePrompt(req)
+	if err := s.aiTaskRepo.Create(task); err != nil {
+		s.quota.Abandon(context.Background(), userID, slot)
+		return nil, err
+	}
+	s.quota.Track(context.Background(), task, slot)
 
-	// 选择AI客户端（续写使用DeepSeek）
-	client := s.deepSeekClient
+	job := &queue.Job{TaskID: task.ID, Type: string(task.Type)}
+	if err := s.taskQueue.Enqueue(context.Background(), job); err != nil {
+		s.failTask(task, fmt.Errorf("%w: %v", ErrEnqueueAITask, err))
+		return nil, ErrEnqueueAITask
+	}
 
-	// 调用AI生成
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 30)
-	result, err := client.Generate(ctx, prompt, req.Length)
+	return &dto.AITaskResponse{
+		TaskID:        task.ID,
+		Status:        string(task.Status),
+		EstimatedTime: estimatedTime,
+	}, nil
+}
+
+// ProcessTask 执行队列中的AI任务
+//
+// 只有成功将任务从pending认领为processing的worker才会执行，重复投递的任务会被直接跳过。
+// 任务本身的失败会记录在AITask上并返回nil；只有无法读写任务记录时才返回错误，此时不应确认消息。
+func (s *aiService) ProcessTask(ctx context.Context, taskID uint) error {
+	task, err := s.aiTaskRepo.FindByID(taskID)
 	if err != nil {
-		s.aiTaskRepo.UpdateError(taskID, err.Error())
-		return
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
 	}
 
-	// 保存结果
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 90)
-	s.aiTaskRepo.UpdateResult(taskID, result)
+	claimed, err := s.aiTaskRepo.Claim(task.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	// 任务被取消时中断进行中的AI请求
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.runningMu.Lock()
+	s.running[task.ID] = cancel
+	s.runningMu.Unlock()
+	defer func() {
+		s.runningMu.Lock()
+		delete(s.running, task.ID)
+		s.runningMu.Unlock()
+	}()
+	go s.watchTask(ctx, task.ID, cancel)
+
+	switch task.Type {
+	case model.AITaskTypeContinue:
+		var req dto.ContinueRequest
+		if s.decodeParameters(task, &req) {
+			s.processContinueTask(ctx, task, &req)
+		}
+	case model.AITaskTypePolish:
+		var req dto.PolishRequest
+		if s.decodeParameters(task, &req) {
+			s.processPolishTask(ctx, task, &req)
+		}
+	case model.AITaskTypeExpand:
+		var req dto.ExpandRequest
+		if s.decodeParameters(task, &req) {
+			s.processExpandTask(ctx, task, &req)
+		}
+	case model.AITaskTypeRewrite:
+		var req dto.RewriteRequest
+		if s.decodeParameters(task, &req) {
+			s.processRewriteTask(ctx, task, &req)
+		}
+	case model.AITaskTypeOutline:
+		var req dto.OutlineRequest
+		if s.decodeParameters(task, &req) {
+			s.processOutlineTask(ctx, task, &req)
+		}
+	case model.AITaskTypeNovelToScreenplay:
+		var req dto.NovelToScreenplayRequest
+		if s.decodeParameters(task, &req) {
+			s.processNovelToScreenplayTask(ctx, task, &req)
+		}
+	case model.AITaskTypeScreenplayToNovel:
+		var req dto.ScreenplayToNovelRequest
+		if s.decodeParameters(task, &req) {
+			s.processScreenplayToNovelTask(ctx, task, &req)
+		}
+	case model.AITaskTypeConsistencyCheck:
+		var req dto.ConsistencyCheckRequest
+		if s.decodeParameters(task, &req) {
+			s.processConsistencyCheckTask(ctx, task, &req)
+		}
+	case model.AITaskTypeSnowflake:
+		var req dto.SnowflakeExpandRequest
+		if s.decodeParameters(task, &req) {
+			s.processSnowflakeTask(ctx, task, &req)
+		}
+	case model.AITaskTypeCharacter:
+		var req dto.CharacterGenerateRequest
+		if s.decodeParameters(task, &req) {
+			s.processCharacterTask(ctx, task, &req)
+		}
+	case model.AITaskTypeChapterSummary:
+		var req dto.ChapterSummaryRequest
+		if s.decodeParameters(task, &req) {
+			s.processChapterSummaryTask(ctx, task, &req)
+		}
+	default:
+		s.failTask(task, fmt.Errorf("unsupported task type: %s", task.Type))
+	}
 
-	// 标记完成
-	now := time.Now()
-	task, _ := s.aiTaskRepo.FindByID(taskID)
-	task.Status = model.AITaskStatusCompleted
-	task.Progress = 100
-	task.CompletedAt = &now
-	s.aiTaskRepo.Update(task)
+	return nil
 }
 
-// processPolishTask 处理润色任务
-func (s *aiService) processPolishTask(taskID uint, req *dto.PolishRequest) {
-	ctx := context.Background()
-
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 10)
-
-	prompt := s.buildPolishPrompt(req)
+// watchTask 任务执行期间定期发送心跳，并在发现任务已被取消时中断执行
+func (s *aiService) watchTask(ctx context.Context, taskID uint, cancel context.CancelFunc) {
+	ticker := time.NewTicker(cancelPollInterval)
+	defer ticker.Stop()
+	lastHeartbeat := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			status, err := s.aiTaskRepo.GetStatus(taskID)
+			if err == nil && status == model.AITaskStatusCancelled {
+				cancel()
+				return
+			}
+			if time.Since(lastHeartbeat) >= heartbeatInterval {
+				s.aiTaskRepo.Touch(taskID)
+				lastHeartbeat = time.Now()
+			}
+		}
+	}
+}
 
-	// 润色使用Claude
-	client := s.claudeClient
+// decodeParameters 解析任务参数，失败时将任务标记为失败
+func (s *aiService) decodeParameters(task *model.AITask, req interface{}) bool {
+	if err := json.Unmarshal([]byte(task.Parameters), req); err != nil {
+		s.failTask(task, fmt.Errorf("invalid task parameters: %w", err))
+		return false
+	}
+	return true
+}
 
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 30)
-	result, err := client.Generate(ctx, prompt, 4096)
+// generateStream 流式调用AI生成，生成过程中定期将已收到的文本写入任务结果并推送给用户（进度30%-90%），
+// 命中缓存时一次性推送缓存的结果
+func (s *aiService) generateStream(ctx context.Context, task *model.AITask, client ai.Client, prompt string, maxTokens int) (*ai.Result, error) {
+	var partial strings.Builder
+	lastFlush := time.Now()
+	lastPush := time.Now()
+	pushed := 0      // 已推送的字节数
+	pushedRunes := 0 // 已推送的字数，作为片段的偏移
+
+	// pushChunk 推送尚未发送的文本片段
+	pushChunk := func() {
+		if partial.Len() == pushed {
+			return
+		}
+		delta := partial.String()[pushed:]
+		s.notify(task, &dto.AITaskEvent{
+			Event:    AITaskEventChunk,
+			Progress: streamProgress(partial.Len(), maxTokens),
+			Delta:    delta,
+			Offset:   pushedRunes,
+		})
+		pushed = partial.Len()
+		pushedRunes += utf8.RuneCountInString(delta)
+		lastPush = time.Now()
+	}
+
+	result, err := s.generateCached(ctx, task, client, prompt, maxTokens, 0, func() (*ai.Result, error) {
+		return client.GenerateStream(ctx, prompt, maxTokens, func(delta string) {
+			partial.WriteString(delta)
+			if time.Since(lastPush) >= streamPushInterval {
+				pushChunk()
+			}
+			if time.Since(lastFlush) < streamFlushInterval {
+				return
+			}
+			lastFlush = time.Now()
+			s.aiTaskRepo.UpdatePartialResult(task.ID, partial.String(), streamProgress(partial.Len(), maxTokens))
+		})
+	})
 	if err != nil {
-		s.aiTaskRepo.UpdateError(taskID, err.Error())
-		return
+		return nil, err
+	}
+	if result.Cached {
+		partial.WriteString(result.Text)
 	}
+	pushChunk()
 
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 90)
-	s.aiTaskRepo.UpdateResult(taskID, result)
+	// 记录实际提供服务的提供商和用量
+	recordUsage(task, result)
 
-	now := time.Now()
-	task, _ := s.aiTaskRepo.FindByID(taskID)
-	task.Status = model.AITaskStatusCompleted
-	task.Progress = 100
-	task.CompletedAt = &now
-	s.aiTaskRepo.Update(task)
+	return result, nil
 }
 
-// processExpandTask 处理扩写任务
-func (s *aiService) processExpandTask(taskID uint, req *dto.ExpandRequest) {
-	ctx := context.Background()
-
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 10)
-
-	prompt := s.buildExpandPrompt(req)
-
-	// 扩写使用DeepSeek
-	client := s.deepSeekClient
+// updateProgress 更新任务为处理中并推送进度
+func (s *aiService) updateProgress(task *model.AITask, progress int) {
+	task.Status = model.AITaskStatusProcessing
+	task.Progress = progress
+	s.aiTaskRepo.UpdateStatus(task.ID, task.Status, progress)
+	s.notify(task, &dto.AITaskEvent{Event: AITaskEventProgress})
+}
 
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 30)
-	result, err := client.Generate(ctx, prompt, req.Length)
+// completeTask 审核并保存结果、标记任务完成并推送完成事件（任务已被取消时不会覆盖状态），结果未通过审核时任务失败
+func (s *aiService) completeTask(task *model.AITask, result string) {
+	result, err := s.moderate(task, result)
 	if err != nil {
-		s.aiTaskRepo.UpdateError(taskID, err.Error())
+		s.rejectTask(task, err)
 		return
 	}
+	s.completeModeratedTask(task, result)
+}
 
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 90)
-	s.aiTaskRepo.UpdateResult(taskID, result)
-
+// completeModeratedTask 标记任务完成并推送完成事件，result须已经过审核
+func (s *aiService) completeModeratedTask(task *model.AITask, result string) {
 	now := time.Now()
-	task, _ := s.aiTaskRepo.FindByID(taskID)
-	task.Status = model.AITaskStatusCompleted
-	task.Progress = 100
+	task.Result = result
 	task.CompletedAt = &now
-	s.aiTaskRepo.Update(task)
-}
-
-// processRewriteTask 处理改写任务
-func (s *aiService) processRewriteTask(taskID uint, req *dto.RewriteRequest) {
-	ctx := context.Background()
-
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 10)
-
-	prompt := s.buildRewritePrompt(req)
-
-	// 改写使用DeepSeek
-	client := s.deepSeekClient
-
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 30)
-	result, err := client.Generate(ctx, prompt, 4096)
-	if err != nil {
-		s.aiTaskRepo.UpdateError(taskID, err.Error())
+	if !s.writeTerminalStatus(task, func() (bool, error) { return s.aiTaskRepo.Complete(task) }) {
 		return
 	}
 
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 90)
-	s.aiTaskRepo.UpdateResult(taskID, result)
-
-	now := time.Now()
-	task, _ := s.aiTaskRepo.FindByID(taskID)
 	task.Status = model.AITaskStatusCompleted
 	task.Progress = 100
-	task.CompletedAt = &now
-	s.aiTaskRepo.Update(task)
+	s.quota.Release(context.Background(), task)
+	s.notify(task, &dto.AITaskEvent{Event: AITaskEventCompleted, Result: result})
 }
 
-// buildContinuePrompt 构建续写提示词
-func (s *aiService) buildContinuePrompt(req *dto.ContinueRequest) string {
-	styleHint := ""
-	if req.Style != "" {
-		styleHint = fmt.Sprintf("\n- 风格要求：%s", req.Style)
+// failTask 标记任务失败并推送失败事件（任务已被取消时不会覆盖状态）
+func (s *aiService) failTask(task *model.AITask, err error) {
+	if errors.Is(err, ErrModerationRejected) {
+		s.rejectTask(task, err)
+		return
 	}
-
-	typeDesc := "小说"
-	if req.Type == "screenplay" {
-		typeDesc = "剧本"
+	if !s.writeTerminalStatus(task, func() (bool, error) { return s.aiTaskRepo.Fail(task, err.Error()) }) {
+		return
 	}
 
-	return fmt.Sprintf(`你是一位经验丰富的%s作家。请根据前文内容进行自然流畅的续写。
-
-【前文内容】
-%s
-
-【续写要求】
-- 续写长度：约%d字
-- 保持与前文的风格、语气、人物性格完全一致
-- 情节发展自然合理，符合逻辑
-- 如果是对话场景，注意对话的真实性和人物特点
-- 如果是叙事场景，注意细节描写和氛围营造%s
-- 直接输出续写内容，不要添加任何解释说明
+	task.Status = model.AITaskStatusFailed
+	task.Error = err.Error()
+	s.quota.Release(context.Background(), task)
+	s.notify(task, &dto.AITaskEvent{Event: AITaskEventFailed, Error: task.Error})
+}
 
-【续写内容】`, typeDesc, req.Context, req.Length, styleHint)
+// writeTerminalStatus 写入任务的结束状态，数据库错误时按间隔重试，返回是否写入成功
+//
+// write返回false表示任务已被取消或已结束，补写已取消任务的用量后返回；多次重试仍失败时记录日志、释放配额并推送失败事件，
+// 数据库中仍为处理中的任务由恢复巡检处理。
+func (s *aiService) writeTerminalStatus(task *model.AITask, write func() (bool, error)) bool {
+	var err error
+	for attempt := 0; attempt < statusWriteAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * statusWriteBackoff)
+		}
+		var written bool
+		written, err = write()
+		if err == nil {
+			if !written && (task.InputTokens+task.OutputTokens > 0 || task.PromptVersion != "") {
+				// 任务已被取消，补写取消前产生的用量和使用的提示词版本；取消时已释放并发名额，这里只计入token预算
+				if err := s.aiTaskRepo.SaveCancelledUsage(task); err != nil {
+					log.Printf("Failed to save cancelled AI task usage: id=%d, err=%v", task.ID, err)
+				}
+				s.quota.AddUsage(context.Background(), task)
+			}
+			return written
+		}
+	}
+
+	log.Printf("Failed to save AI task status: id=%d, err=%v", task.ID, err)
+	s.quota.Release(context.Background(), task)
+	s.notify(task, &dto.AITaskEvent{Event: AITaskEventFailed, Error: "failed to save task status"})
+	return false
 }
 
-// buildPolishPrompt 构建润色提示词
-func (s *aiService) buildPolishPrompt(req *dto.PolishRequest) string {
-	styleHint := ""
-	if req.Style != "" {
-		styleHint = fmt.Sprintf("\n- 目标风格：%s", req.Style)
+// notify 向任务所有者推送事件，未显式设置的任务信息从task补全
+func (s *aiService) notify(task *model.AITask, event *dto.AITaskEvent) {
+	event.TaskID = task.ID
+	event.WorkID = task.WorkID
+	event.TaskType = string(task.Type)
+	event.Status = string(task.Status)
+	if event.Progress == 0 {
+		event.Progress = task.Progress
 	}
+	s.notifier.NotifyAITask(task.UserID, event)
+}
 
-	return fmt.Sprintf(`你是一位专业的文字编辑。请对以下内容进行润色优化，提升文字质量和表达效果。
+// streamProgress 根据已生成长度估算进度，范围30-89
+func streamProgress(generated, maxTokens int) int {
+	if maxTokens <= 0 {
+		maxTokens = 4096
+	}
+	// 中文约每个token对应3字节UTF-8
+	progress := 30 + generated*60/(maxTokens*3)
+	if progress > 89 {
+		progress = 89
+	}
+	return progress
+}
 
-【原文内容】
-%s
+// processContinueTask 处理续写任务
+func (s *aiService) processContinueTask(ctx context.Context, task *model.AITask, req *dto.ContinueRequest) {
+	// 更新任务状态为处理中
+	s.updateProgress(task, 10)
 
-【润色要求】
-- 优化词汇选择，使用更精准、生动的表达
-- 改善句式结构，增强语言的节奏感和流畅度
-- 消除冗余表达，使文字更加简洁有力
-- 保持原文的核心意思和情感基调不变
-- 修正可能存在的语法错误或不通顺之处%s
-- 直接输出润色后的内容，不要添加任何解释说明
+	// 指定章节时从章节读取前文
+	data := &promptData{Req: req}
+	if req.ChapterID != 0 {
+		chapter, part, err := s.loadPreceding(req)
+		if err != nil {
+			s.failTask(task, err)
+			return
+		}
+		data.Chapter, data.Part = chapter, part
+	}
 
-【润色后内容】`, req.Content, styleHint)
-}
+	// 构建提示词
+	prompt, err := s.renderPrompt(task, string(task.Type), data)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
 
-// buildExpandPrompt 构建扩写提示词
-func (s *aiService) buildExpandPrompt(req *dto.ExpandRequest) string {
-	focusHint := ""
-	if req.Focus != "" {
-		focusHint = fmt.Sprintf("\n- 扩写重点：%s", req.Focus)
+	// 调用AI生成（续写默认使用DeepSeek）
+	s.updateProgress(task, 30)
+	text, err := s.generateText(ctx, task, prompt, req.Length, req.AICandidateOptions)
+	if err != nil {
+		s.failTask(task, err)
+		return
 	}
 
-	return fmt.Sprintf(`你是一位擅长细节描写的作家。请对以下内容进行扩写，丰富细节和描写。
+	// 保存结果并标记完成
+	s.completeTask(task, text)
+}
+
+// processPolishTask 处理润色任务
+func (s *aiService) processPolishTask(ctx context.Context, task *model.AITask, req *dto.PolishRequest) {
+	s.updateProgress(task, 10)
 
-【原文内容】
-%s
+	prompt, err := s.renderPrompt(task, string(task.Type), &promptData{Req: req})
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
 
-【扩写要求】
-- 扩写后长度：约%d字
-- 增加环境描写、人物动作、心理活动等细节
-- 丰富感官描写（视觉、听觉、触觉等）
-- 保持原有情节主线和人物性格不变
-- 扩写内容要自然融入，不显突兀%s
-- 直接输出扩写后的完整内容，不要添加任何说明
+	// 调用AI生成（润色默认使用Claude）
+	s.updateProgress(task, 30)
+	text, err := s.generateText(ctx, task, prompt, 4096, req.AICandidateOptions)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
 
-【扩写后内容】`, req.Content, req.Length, focusHint)
+	s.completeTask(task, text)
 }
 
-// buildRewritePrompt 构建改写提示词
-func (s *aiService) buildRewritePrompt(req *dto.RewriteRequest) string {
-	hints := ""
-	if req.Style != "" {
-		hints += fmt.Sprintf("\n- 目标风格：%s", req.Style)
+// processExpandTask 处理扩写任务
+func (s *aiService) processExpandTask(ctx context.Context, task *model.AITask, req *dto.ExpandRequest) {
+	s.updateProgress(task, 10)
+
+	prompt, err := s.renderPrompt(task, string(task.Type), &promptData{Req: req})
+	if err != nil {
+		s.failTask(task, err)
+		return
 	}
-	if req.Tone != "" {
-		hints += fmt.Sprintf("\n- 目标语气：%s", req.Tone)
+
+	// 调用AI生成（扩写默认使用DeepSeek）
+	s.updateProgress(task, 30)
+	text, err := s.generateText(ctx, task, prompt, req.Length, req.AICandidateOptions)
+	if err != nil {
+		s.failTask(task, err)
+		return
 	}
 
-	return fmt.Sprintf(`你是一位文字改写专家。请对以下内容进行改写，改变表达方式但保持核心意思。
+	s.completeTask(task, text)
+}
 
-【原文内容】
-%s
+// processRewriteTask 处理改写任务
+func (s *aiService) processRewriteTask(ctx context.Context, task *model.AITask, req *dto.RewriteRequest) {
+	s.updateProgress(task, 10)
 
-【改写要求】
-- 使用不同的词汇和句式结构表达相同的意思
-- 可以调整叙述角度或表达顺序
-- 保持原文的核心信息和主要观点
-- 改写后的文字应该流畅自然，不显生硬%s
-- 直接输出改写后的内容，不要添加任何说明
+	prompt, err := s.renderPrompt(task, string(task.Type), &promptData{Req: req})
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
 
-【改写后内容】`, req.Content, hints)
+	// 调用AI生成（改写默认使用DeepSeek）
+	s.updateProgress(task, 30)
+	text, err := s.generateText(ctx, task, prompt, 4096, req.AICandidateOptions)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	s.completeTask(task, text)
 }
 
 // GenerateOutline AI大纲生成
@@ -444,29 +737,8 @@ func (s *aiService) GenerateOutline(userID uint, req *dto.OutlineRequest) (*dto.
 		return nil, err
 	}
 
-	// 创建任务记录
-	params, _ := json.Marshal(req)
-	task := &model.AITask{
-		UserID:     userID,
-		WorkID:     req.WorkID,
-		Type:       model.AITaskTypeOutline,
-		Status:     model.AITaskStatusPending,
-		Parameters: string(params),
-		Progress:   0,
-	}
-
-	if err := s.aiTaskRepo.Create(task); err != nil {
-		return nil, err
-	}
-
-	// 异步处理任务
-	go s.processOutlineTask(task.ID, req)
-
-	return &dto.AITaskResponse{
-		TaskID:        task.ID,
-		Status:        string(task.Status),
-		EstimatedTime: 60,
-	}, nil
+	// 创建任务并加入队列
+	return s.createTask(userID, req.WorkID, model.AITaskTypeOutline, req, 60)
 }
 
 // ConvertNovelToScreenplay 小说转剧本
@@ -484,30 +756,14 @@ func (s *aiService) ConvertNovelToScreenplay(userID uint, req *dto.NovelToScreen
 	if work.Type != "novel" {
 		return nil, errors.New("work type must be novel")
 	}
-
-	// 创建任务记录
-	params, _ := json.Marshal(req)
-	task := &model.AITask{
-		UserID:     userID,
-		WorkID:     req.WorkID,
-		Type:       model.AITaskTypeNovelToScreenplay,
-		Status:     model.AITaskStatusPending,
-		Parameters: string(params),
-		Progress:   0,
-	}
-
-	if err := s.aiTaskRepo.Create(task); err != nil {
+	if count, err := s.chapterRepo.CountByWorkID(work.ID); err != nil {
 		return nil, err
+	} else if count == 0 {
+		return nil, ErrEmptyWork
 	}
 
-	// 异步处理任务
-	go s.processNovelToScreenplayTask(task.ID, req)
-
-	return &dto.AITaskResponse{
-		TaskID:        task.ID,
-		Status:        string(task.Status),
-		EstimatedTime: 120,
-	}, nil
+	// 创建任务并加入队列
+	return s.createTask(userID, req.WorkID, model.AITaskTypeNovelToScreenplay, req, 120)
 }
 
 // ConvertScreenplayToNovel 剧本转小说
@@ -525,220 +781,53 @@ func (s *aiService) ConvertScreenplayToNovel(userID uint, req *dto.ScreenplayToN
 	if work.Type != "screenplay" {
 		return nil, errors.New("work type must be screenplay")
 	}
-
-	// 创建任务记录
-	params, _ := json.Marshal(req)
-	task := &model.AITask{
-		UserID:     userID,
-		WorkID:     req.WorkID,
-		Type:       model.AITaskTypeScreenplayToNovel,
-		Status:     model.AITaskStatusPending,
-		Parameters: string(params),
-		Progress:   0,
-	}
-
-	if err := s.aiTaskRepo.Create(task); err != nil {
+	if count, err := s.chapterRepo.CountByWorkID(work.ID); err != nil {
 		return nil, err
+	} else if count == 0 {
+		return nil, ErrEmptyWork
 	}
 
-	// 异步处理任务
-	go s.processScreenplayToNovelTask(task.ID, req)
-
-	return &dto.AITaskResponse{
-		TaskID:        task.ID,
-		Status:        string(task.Status),
-		EstimatedTime: 120,
-	}, nil
+	// 创建任务并加入队列
+	return s.createTask(userID, req.WorkID, model.AITaskTypeScreenplayToNovel, req, 120)
 }
 
-// processOutlineTask 处理大纲生成任务
-func (s *aiService) processOutlineTask(taskID uint, req *dto.OutlineRequest) {
-	ctx := context.Background()
-
+// processOutlineTask 处理大纲生成任务：声明JSON输出的模板版本生成结构化大纲，早期的纯文本版本直接保存生成的文本
+func (s *aiService) processOutlineTask(ctx context.Context, task *model.AITask, req *dto.OutlineRequest) {
 	// 更新任务状态为处理中
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 10)
-
-	// 构建提示词
-	prompt := s.buildOutlinePrompt(req)
-
-	// 大纲生成使用Claude（需要高质量和结构化能力）
-	client := s.claudeClient
+	s.updateProgress(task, 10)
 
-	// 调用AI生成
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 30)
-	result, err := client.Generate(ctx, prompt, 4096)
+	data := &promptData{Req: req}
+	rendered, err := s.renderTemplate(task, string(task.Type), data)
 	if err != nil {
-		s.aiTaskRepo.UpdateError(taskID, err.Error())
+		s.failTask(task, err)
 		return
 	}
 
-	// 保存结果
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 90)
-	s.aiTaskRepo.UpdateResult(taskID, result)
-
-	// 标记完成
-	now := time.Now()
-	task, _ := s.aiTaskRepo.FindByID(taskID)
-	task.Status = model.AITaskStatusCompleted
-	task.Progress = 100
-	task.CompletedAt = &now
-	s.aiTaskRepo.Update(task)
-}
-
-// processNovelToScreenplayTask 处理小说转剧本任务
-func (s *aiService) processNovelToScreenplayTask(taskID uint, req *dto.NovelToScreenplayRequest) {
-	ctx := context.Background()
-
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 10)
-
-	// 获取作品内容（这里需要获取所有章节内容）
-	// TODO: 实现获取完整作品内容的逻辑
-	work, err := s.workRepo.FindByID(req.WorkID)
-	if err != nil {
-		s.aiTaskRepo.UpdateError(taskID, err.Error())
-		return
-	}
-
-	// 构建提示词
-	prompt := s.buildNovelToScreenplayPrompt(work, req)
-
-	// 格式转换使用Claude（需要精确控制）
-	client := s.claudeClient
-
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 30)
-	result, err := client.Generate(ctx, prompt, 8192)
-	if err != nil {
-		s.aiTaskRepo.UpdateError(taskID, err.Error())
-		return
-	}
-
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 90)
-	s.aiTaskRepo.UpdateResult(taskID, result)
-
-	now := time.Now()
-	task, _ := s.aiTaskRepo.FindByID(taskID)
-	task.Status = model.AITaskStatusCompleted
-	task.Progress = 100
-	task.CompletedAt = &now
-	s.aiTaskRepo.Update(task)
-}
-
-// processScreenplayToNovelTask 处理剧本转小说任务
-func (s *aiService) processScreenplayToNovelTask(taskID uint, req *dto.ScreenplayToNovelRequest) {
-	ctx := context.Background()
-
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 10)
-
-	// 获取作品内容
-	work, err := s.workRepo.FindByID(req.WorkID)
-	if err != nil {
-		s.aiTaskRepo.UpdateError(taskID, err.Error())
+	// 调用AI生成（大纲生成默认使用Claude，需要高质量和结构化能力）
+	s.updateProgress(task, 30)
+	if rendered.Output != prompt.OutputJSON {
+		result, err := s.generateStream(ctx, task, s.clientFor(task.Type), rendered.Text, outlineMaxTokens(req.NumChapters))
+		if err != nil {
+			s.failTask(task, err)
+			return
+		}
+		s.completeTask(task, result.Text)
 		return
 	}
 
-	// 构建提示词
-	prompt := s.buildScreenplayToNovelPrompt(work, req)
-
-	// 格式转换使用Claude
-	client := s.claudeClient
-
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 30)
-	result, err := client.Generate(ctx, prompt, 8192)
+	// 输出不是合法的大纲JSON时重试
+	var outline *dto.OutlineResult
+	err = s.generateJSON(ctx, task, data, outlineMaxTokens(req.NumChapters), func(output string) error {
+		var err error
+		outline, err = parseOutline(output, req.NumChapters)
+		return err
+	})
 	if err != nil {
-		s.aiTaskRepo.UpdateError(taskID, err.Error())
+		s.failTask(task, err)
 		return
 	}
 
-	s.aiTaskRepo.UpdateStatus(taskID, model.AITaskStatusProcessing, 90)
-	s.aiTaskRepo.UpdateResult(taskID, result)
-
-	now := time.Now()
-	task, _ := s.aiTaskRepo.FindByID(taskID)
-	task.Status = model.AITaskStatusCompleted
-	task.Progress = 100
-	task.CompletedAt = &now
-	s.aiTaskRepo.Update(task)
-}
-
-// buildOutlinePrompt 构建大纲生成提示词
-func (s *aiService) buildOutlinePrompt(req *dto.OutlineRequest) string {
-	styleHint := ""
-	if req.Style != "" {
-		styleHint = fmt.Sprintf("，风格要求：%s", req.Style)
-	}
-
-	return fmt.Sprintf(`你是一位专业的%s小说策划师。请根据以下信息生成一个详细的小说大纲%s。
-
-主题：%s
-章节数量：%d章
-
-要求：
-1. 生成完整的故事梗概（200-300字）
-2. 为每一章生成标题和内容概要（每章100-150字）
-3. 设定2-3个主要角色及其基本信息
-4. 标注3-5个关键情节点
-5. 确保情节连贯、逻辑合理
-6. 输出格式为结构化的文本，便于阅读
-
-请开始生成大纲：`, req.Genre, styleHint, req.Topic, req.NumChapters)
-}
-
-// buildNovelToScreenplayPrompt 构建小说转剧本提示词
-func (s *aiService) buildNovelToScreenplayPrompt(work *model.Work, req *dto.NovelToScreenplayRequest) string {
-	durationHint := ""
-	if req.TargetDuration > 0 {
-		durationHint = fmt.Sprintf("，目标时长约%d分钟", req.TargetDuration)
-	}
-	scenesHint := ""
-	if req.NumScenes > 0 {
-		scenesHint = fmt.Sprintf("，分为约%d个场景", req.NumScenes)
-	}
-
-	return fmt.Sprintf(`你是一位专业的编剧。请将以下小说内容转换为剧本格式%s%s。
-
-小说标题：%s
-小说内容：
-（注：实际使用时需要加载完整章节内容）
-
-剧本格式要求：
-1. 场景标题格式：INT./EXT. 地点 - 时间
-2. 场景描述：简洁的环境和氛围描写
-3. 人物对话格式：
-   角色名
-   （表情/动作）
-   对话内容
-4. 动作描述：用现在时描述人物动作
-5. 保留原作核心情节和人物性格
-6. 适当调整叙事节奏以适应视觉呈现
-
-请开始转换：`, durationHint, scenesHint, work.Title)
-}
-
-// buildScreenplayToNovelPrompt 构建剧本转小说提示词
-func (s *aiService) buildScreenplayToNovelPrompt(work *model.Work, req *dto.ScreenplayToNovelRequest) string {
-	chaptersHint := ""
-	if req.NumChapters > 0 {
-		chaptersHint = fmt.Sprintf("，分为%d章", req.NumChapters)
-	}
-	wordsHint := ""
-	if req.WordPerChapter > 0 {
-		wordsHint = fmt.Sprintf("，每章约%d字", req.WordPerChapter)
-	}
-
-	return fmt.Sprintf(`你是一位专业的小说作家。请将以下剧本内容转换为小说格式%s%s。
-
-剧本标题：%s
-剧本内容：
-（注：实际使用时需要加载完整章节内容）
-
-小说格式要求：
-1. 将场景描述转换为生动的环境描写
-2. 将对话转换为小说对话格式，添加对话标签和动作描写
-3. 增加人物心理描写和内心独白
-4. 丰富细节描写，增强画面感
-5. 保持原作情节和人物性格
-6. 使用第三人称叙事（或根据原作风格调整）
-7. 注意章节划分的合理性
-
-请开始转换：`, chaptersHint, wordsHint, work.Title)
+	// 保存结果并标记完成
+	result, _ := json.Marshal(outline)
+	s.completeTask(task, string(result))
 }
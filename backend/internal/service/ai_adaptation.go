@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+)
+
+const (
+	defaultWordPerChapter = 3000
+	// adaptationTitleSuffix 改编生成的新作品标题后缀
+	adaptationTitleSuffix = "（小说版）"
+)
+
+var (
+	// sceneHeadingPattern 剧本场景标题，优先在场景边界处切分章节
+	sceneHeadingPattern = regexp.MustCompile(`^(INT|EXT|I/E|内景|外景|内/外景|【场景】|第[0-9一二三四五六七八九十百千]+场|场景\s*[0-9一二三四五六七八九十百千]+)`)
+	// chapterTitlePrefixPattern 模型输出标题中的“标题：”“第X章”等前缀
+	chapterTitlePrefixPattern = regexp.MustCompile(`^(#+\s*)?(标题[:：]\s*)?(第[0-9一二三四五六七八九十百千]+章[\s:：]*)?`)
+)
+
+// processScreenplayToNovelTask 处理剧本转小说任务：将剧本平均分为NumChapters段分别改写为章节，写入新的小说作品
+func (s *aiService) processScreenplayToNovelTask(ctx context.Context, task *model.AITask, req *dto.ScreenplayToNovelRequest) {
+	s.updateProgress(task, 5)
+
+	work, err := s.workRepo.FindByID(req.WorkID)
+	if err != nil {
+		s.failTask(task, ErrWorkNotFound)
+		return
+	}
+	chapters, err := s.chapterRepo.FindByWorkID(work.ID)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+	characters, err := s.characterRepo.FindByWorkID(work.ID)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	var texts []string
+	for _, ch := range chapters {
+		if text := htmlToText(ch.Content); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	if len(texts) == 0 {
+		s.failTask(task, ErrEmptyWork)
+		return
+	}
+
+	// 章节数默认与剧本的章节数一致，每章字数默认沿用作品设置
+	numChapters := req.NumChapters
+	if numChapters <= 0 {
+		numChapters = len(texts)
+	}
+	wordPerChapter := req.WordPerChapter
+	if wordPerChapter <= 0 {
+		wordPerChapter = work.WordPerChapter
+	}
+	if wordPerChapter <= 0 {
+		wordPerChapter = defaultWordPerChapter
+	}
+
+	segments := splitEvenly(strings.Join(texts, "\n"), numChapters)
+	prompts := make([]string, len(segments))
+	for i, segment := range segments {
+		part := &convertPart{
+			Index: i + 1,
+			Total: len(segments),
+			Text:  segment,
+			Words: wordPerChapter,
+		}
+		if i > 0 {
+			part.Previous = tailRunes(segments[i-1], previousTailChars)
+		}
+		prompts[i], err = s.renderPrompt(task, string(task.Type), &promptData{
+			Work:       work,
+			Req:        req,
+			Characters: characters,
+			Part:       part,
+		})
+		if err != nil {
+			s.failTask(task, err)
+			return
+		}
+	}
+
+	s.updateProgress(task, 10)
+	outputs, err := s.generateParts(ctx, task, prompts, s.chapterMaxTokens(wordPerChapter), 10, 90)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	// 任务在生成期间被取消时不再写入新作品
+	if err := ctx.Err(); err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	// 章节写入新作品前先审核，任务结果中只有标题和ID，完成任务时的审核覆盖不到正文
+	for i := range outputs {
+		if outputs[i], err = s.moderate(task, outputs[i]); err != nil {
+			s.failTask(task, err)
+			return
+		}
+	}
+
+	adaptation, err := s.saveAdaptation(work, characters, outputs, wordPerChapter)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	result, _ := json.Marshal(adaptation)
+	s.completeTask(task, string(result))
+}
+
+// saveAdaptation 创建改编后的小说作品，写入章节并复制角色
+func (s *aiService) saveAdaptation(source *model.Work, characters []model.Character, outputs []string, wordPerChapter int) (*dto.AdaptationResult, error) {
+	sourceID := source.ID
+	chapters := make([]*model.Chapter, 0, len(outputs))
+	totalWords := 0
+	for i, output := range outputs {
+		title, body := parseChapterOutput(output, i+1)
+		chapter := &model.Chapter{
+			Title:    title,
+			Content:  textToHTML(body),
+			Words:    countRunes(body),
+			OrderNum: i + 1,
+			Status:   model.ChapterStatusDraft,
+		}
+		totalWords += chapter.Words
+		chapters = append(chapters, chapter)
+	}
+
+	copies := make([]*model.Character, 0, len(characters))
+	for _, c := range characters {
+		copies = append(copies, &model.Character{
+			Name:        c.Name,
+			Role:        c.Role,
+			Description: c.Description,
+		})
+	}
+
+	novel := &model.Work{
+		UserID:         source.UserID,
+		Type:           model.WorkTypeNovel,
+		Title:          source.Title + adaptationTitleSuffix,
+		Topic:          source.Topic,
+		Genre:          source.Genre,
+		Status:         model.WorkStatusDraft,
+		Words:          totalWords,
+		NumChapters:    len(chapters),
+		WordPerChapter: wordPerChapter,
+		SourceWorkID:   &sourceID,
+	}
+	// 作品、章节和角色在同一事务中创建，失败时不会留下不完整的作品
+	if err := s.workRepo.CreateWithContent(novel, chapters, copies); err != nil {
+		return nil, fmt.Errorf("failed to create adapted work: %w", err)
+	}
+
+	result := &dto.AdaptationResult{
+		WorkID:   novel.ID,
+		Title:    novel.Title,
+		Chapters: make([]dto.AdaptationChapter, 0, len(chapters)),
+	}
+	for _, chapter := range chapters {
+		result.Chapters = append(result.Chapters, dto.AdaptationChapter{
+			ChapterID: chapter.ID,
+			Title:     chapter.Title,
+			Order:     chapter.OrderNum,
+			Words:     chapter.Words,
+		})
+	}
+	return result, nil
+}
+
+// splitEvenly 将剧本按字数平均切分为n段，优先在场景标题处切分
+func splitEvenly(text string, n int) []string {
+	lines := strings.Split(text, "\n")
+	offsets := make([]int, len(lines))
+	total := 0
+	for i, line := range lines {
+		offsets[i] = total
+		total += utf8.RuneCountInString(line) + 1
+	}
+
+	// 最近的场景标题偏离理想位置超过半段时改为在任意段落处切分
+	tolerance := total / (2 * n)
+	cuts := []int{0}
+	for k := 1; k < n; k++ {
+		ideal := total * k / n
+		prev := cuts[len(cuts)-1]
+		best := nearestLine(lines, offsets, prev, ideal, true)
+		if best < 0 || abs(offsets[best]-ideal) > tolerance {
+			best = nearestLine(lines, offsets, prev, ideal, false)
+		}
+		if best < 0 {
+			break
+		}
+		cuts = append(cuts, best)
+	}
+
+	segments := make([]string, 0, len(cuts))
+	for i, start := range cuts {
+		end := len(lines)
+		if i+1 < len(cuts) {
+			end = cuts[i+1]
+		}
+		if segment := strings.TrimSpace(strings.Join(lines[start:end], "\n")); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// nearestLine 在prev之后查找起始位置最接近ideal的非空行，headingOnly时只考虑场景标题行
+func nearestLine(lines []string, offsets []int, prev, ideal int, headingOnly bool) int {
+	best := -1
+	bestDist := 0
+	for i := prev + 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || (headingOnly && !sceneHeadingPattern.MatchString(line)) {
+			continue
+		}
+		dist := abs(offsets[i] - ideal)
+		if best < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// abs 绝对值
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// parseChapterOutput 解析模型输出的章节：第一行为标题，其余为正文
+func parseChapterOutput(output string, order int) (title, body string) {
+	text := strings.TrimSpace(output)
+	first, rest, _ := strings.Cut(text, "\n")
+	title = strings.TrimSpace(chapterTitlePrefixPattern.ReplaceAllString(strings.TrimSpace(first), ""))
+	if title == "" || utf8.RuneCountInString(title) > 50 {
+		// 没有按要求输出标题时保留全部内容作为正文
+		return fmt.Sprintf("第%d章", order), text
+	}
+	return title, strings.TrimSpace(rest)
+}
+
+// chapterMaxTokens 按目标字数估算每章输出的最大token数
+func (s *aiService) chapterMaxTokens(wordPerChapter int) int {
+	tokens := wordPerChapter * 2
+	if tokens < 1024 {
+		tokens = 1024
+	}
+	if limit := s.convertMaxTokens(); tokens > limit {
+		tokens = limit
+	}
+	return tokens
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/internal/repository"
+)
+
+var (
+	ErrAITaskNotApplicable   = errors.New("AI task result cannot be applied")
+	ErrChapterChanged        = errors.New("chapter content has changed")
+	ErrAIApplicationNotFound = errors.New("AI application not found")
+	ErrAIApplicationUndone   = errors.New("AI application already undone")
+	ErrAIApplicationConflict = errors.New("applied text has been modified")
+)
+
+// applicableTaskTypes 结果可以直接写入章节的任务类型
+var applicableTaskTypes = map[model.AITaskType]bool{
+	model.AITaskTypeContinue: true,
+	model.AITaskTypePolish:   true,
+	model.AITaskTypeExpand:   true,
+	model.AITaskTypeRewrite:  true,
+}
+
+// ApplyTask 将任务结果替换到章节的指定范围，Preview时只返回差异
+func (s *aiService) ApplyTask(userID, taskID uint, req *dto.AIApplyRequest) (*dto.AIApplyResponse, error) {
+	task, err := s.findOwnedTask(userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != model.AITaskStatusCompleted || !applicableTaskTypes[task.Type] {
+		return nil, ErrAITaskNotApplicable
+	}
+
+	chapter, err := s.findWorkChapter(task.WorkID, req.ChapterID)
+	if err != nil {
+		return nil, err
+	}
+	hash := contentHash(chapter.Content)
+	if req.ContentHash != "" && req.ContentHash != hash {
+		return nil, ErrChapterChanged
+	}
+
+	inserted := strings.TrimSpace(strings.ReplaceAll(task.Result, "\r\n", "\n"))
+	content, removed, err := replaceText(chapter.Content, req.Start, req.End, inserted)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.AIApplyResponse{
+		ChapterID:   chapter.ID,
+		Preview:     req.Preview,
+		Start:       req.Start,
+		End:         req.End,
+		Removed:     removed,
+		Inserted:    inserted,
+		Diff:        diffText(removed, inserted),
+		ContentHash: hash,
+	}
+	if req.Preview {
+		return resp, nil
+	}
+
+	saved, err := s.saveService.ManualSave(userID, chapter.WorkID, &dto.SaveRequest{
+		Type:    "chapter",
+		ID:      chapter.ID,
+		Content: content,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	application := &model.AIApplication{
+		TaskID:        task.ID,
+		UserID:        userID,
+		WorkID:        chapter.WorkID,
+		ChapterID:     chapter.ID,
+		Start:         req.Start,
+		End:           req.End,
+		Removed:       removed,
+		Inserted:      inserted,
+		ContentBefore: chapter.Content,
+		ContentHash:   contentHash(content),
+	}
+	if err := s.applicationRepo.Create(application); err != nil {
+		return nil, err
+	}
+
+	resp.ApplicationID = application.ID
+	resp.ContentHash = application.ContentHash
+	resp.Words = saved.Words
+	resp.SavedAt = &saved.SavedAt
+	return resp, nil
+}
+
+// UndoApplication 撤销一次应用：章节未再修改时恢复应用前的内容，
+// 否则只要写入的文本仍在原位置就将其替换回原文
+func (s *aiService) UndoApplication(userID, applicationID uint) (*dto.AIApplyResponse, error) {
+	application, err := s.applicationRepo.FindByID(applicationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAIApplicationNotFound) {
+			return nil, ErrAIApplicationNotFound
+		}
+		return nil, err
+	}
+	if application.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if application.UndoneAt != nil {
+		return nil, ErrAIApplicationUndone
+	}
+
+	chapter, err := s.chapterRepo.FindByID(application.ChapterID)
+	if err != nil {
+		return nil, ErrChapterNotFound
+	}
+
+	end := application.Start + utf8.RuneCountInString(application.Inserted)
+	content := application.ContentBefore
+	if contentHash(chapter.Content) != application.ContentHash {
+		var current string
+		content, current, err = replaceText(chapter.Content, application.Start, end, application.Removed)
+		if err != nil || current != application.Inserted {
+			return nil, ErrAIApplicationConflict
+		}
+	}
+
+	saved, err := s.saveService.ManualSave(userID, chapter.WorkID, &dto.SaveRequest{
+		Type:    "chapter",
+		ID:      chapter.ID,
+		Content: content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 并发撤销时两次写入的内容相同，只有一次会被记录
+	undone, err := s.applicationRepo.MarkUndone(application.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !undone {
+		return nil, ErrAIApplicationUndone
+	}
+
+	return &dto.AIApplyResponse{
+		ApplicationID: application.ID,
+		ChapterID:     chapter.ID,
+		Start:         application.Start,
+		End:           end,
+		Removed:       application.Inserted,
+		Inserted:      application.Removed,
+		Diff:          diffText(application.Inserted, application.Removed),
+		ContentHash:   contentHash(content),
+		Words:         saved.Words,
+		SavedAt:       &saved.SavedAt,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jugo/backend/config"
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/pkg/ai"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	cacheKeyPrefix = "jugo:ai_cache:"
+	// defaultCacheTTL 未配置ttl时缓存结果的保留时间
+	defaultCacheTTL = 24 * time.Hour
+)
+
+// aiCache 基于Redis的生成结果缓存，按内容寻址，Redis不可用时直接调用提供商
+type aiCache struct {
+	rdb *redis.Client
+	cfg *config.AICacheConfig
+}
+
+// newAICache 创建结果缓存，未启用时返回nil（nil上的方法均为空操作）
+func newAICache(rdb *redis.Client, cfg *config.AICacheConfig) *aiCache {
+	if rdb == nil || !cfg.Enabled {
+		return nil
+	}
+	return &aiCache{rdb: rdb, cfg: cfg}
+}
+
+// Get 读取缓存的结果，未命中或读取失败时返回nil
+func (c *aiCache) Get(ctx context.Context, key string) *ai.Result {
+	if c == nil {
+		return nil
+	}
+	data, err := c.rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Failed to read AI cache: key=%s, err=%v", key, err)
+		}
+		return nil
+	}
+	var result ai.Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return &result
+}
+
+// Set 写入生成结果
+func (c *aiCache) Set(ctx context.Context, key string, result *ai.Result) {
+	if c == nil || result.Text == "" {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	if err := c.rdb.Set(ctx, key, data, c.ttl()).Err(); err != nil {
+		log.Printf("Failed to write AI cache: key=%s, err=%v", key, err)
+	}
+}
+
+// ttl 缓存结果的保留时间
+func (c *aiCache) ttl() time.Duration {
+	if c.cfg.TTL <= 0 {
+		return defaultCacheTTL
+	}
+	return time.Duration(c.cfg.TTL) * time.Second
+}
+
+// cacheKey 由提供商、模型、模板版本和提示词哈希组成的缓存键；
+// variant区分同一提示词的多个候选，避免各候选得到相同的结果
+func cacheKey(client ai.Client, version, prompt string, maxTokens, variant int) string {
+	promptHash := sha256.Sum256([]byte(prompt))
+	h := sha256.New()
+	for _, part := range []string{
+		client.Name(),
+		ai.ModelName(client),
+		version,
+		hex.EncodeToString(promptHash[:]),
+		strconv.Itoa(maxTokens),
+		strconv.Itoa(variant),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return cacheKeyPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// generateCached 相同的请求命中缓存时直接返回缓存结果（不计用量），否则调用generate并缓存结果；
+// 请求设置了noCache时跳过读取缓存，生成的结果仍会刷新缓存
+func (s *aiService) generateCached(ctx context.Context, task *model.AITask, client ai.Client, prompt string, maxTokens, variant int, generate func() (*ai.Result, error)) (*ai.Result, error) {
+	if s.cache == nil {
+		return generate()
+	}
+
+	key := cacheKey(client, task.PromptVersion, prompt, maxTokens, variant)
+	if !noCache(task) {
+		if result := s.cache.Get(ctx, key); result != nil {
+			result.Usage = ai.Usage{}
+			result.Cost = 0
+			result.Cached = true
+			return result, nil
+		}
+	}
+
+	result, err := generate()
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Set(ctx, key, result)
+	return result, nil
+}
+
+// noCache 任务参数中是否设置了noCache
+func noCache(task *model.AITask) bool {
+	var opts dto.AICacheOptions
+	if err := json.Unmarshal([]byte(task.Parameters), &opts); err != nil {
+		return false
+	}
+	return opts.NoCache
+}
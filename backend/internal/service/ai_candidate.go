@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/internal/repository"
+	"github.com/jugo/backend/pkg/ai"
+)
+
+var (
+	ErrUnknownProvider         = errors.New("unknown AI provider")
+	ErrAITaskCandidateNotFound = errors.New("AI task candidate not found")
+	ErrAITaskCandidateFailed   = errors.New("AI task candidate failed")
+	ErrAITaskNotCompleted      = errors.New("AI task is not completed")
+)
+
+// validateCandidates 验证请求中指定的提供商均已注册
+func (s *aiService) validateCandidates(opts dto.AICandidateOptions) error {
+	for _, name := range opts.Providers {
+		if _, ok := s.providers.Get(name); !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+		}
+	}
+	return nil
+}
+
+// candidateClient 第i个候选使用的客户端：指定了提供商时依次轮流使用，否则使用任务类型的默认链路
+func (s *aiService) candidateClient(task *model.AITask, opts dto.AICandidateOptions, i int) (ai.Client, error) {
+	if len(opts.Providers) == 0 {
+		return s.clientFor(task.Type), nil
+	}
+	name := opts.Providers[i%len(opts.Providers)]
+	client, ok := s.providerClient(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return client, nil
+}
+
+// generateText 生成任务结果：只需一个候选时流式生成；
+// 需要多个候选时并行生成并保存全部候选，返回第一个成功的候选（默认选中）
+func (s *aiService) generateText(ctx context.Context, task *model.AITask, prompt string, maxTokens int, opts dto.AICandidateOptions) (string, error) {
+	n := opts.CandidateCount()
+	if n <= 1 {
+		client, err := s.candidateClient(task, opts, 0)
+		if err != nil {
+			return "", err
+		}
+		result, err := s.generateStream(ctx, task, client, prompt, maxTokens)
+		if err != nil {
+			return "", err
+		}
+		return result.Text, nil
+	}
+
+	clients := make([]ai.Client, n)
+	for i := range clients {
+		client, err := s.candidateClient(task, opts, i)
+		if err != nil {
+			return "", err
+		}
+		clients[i] = client
+	}
+
+	candidates := make([]*model.AITaskCandidate, n)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+	for i, client := range clients {
+		wg.Add(1)
+		go func(i int, client ai.Client) {
+			defer wg.Done()
+			result, err := s.generateCached(ctx, task, client, prompt, maxTokens, i, func() (*ai.Result, error) {
+				return client.Generate(ctx, prompt, maxTokens)
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			candidate := &model.AITaskCandidate{TaskID: task.ID, Index: i, Provider: client.Name()}
+			if err != nil {
+				candidate.Error = err.Error()
+			} else {
+				candidate.Provider = result.Provider
+				candidate.Model = result.Model
+				candidate.Text = result.Text
+				candidate.InputTokens = result.Usage.InputTokens
+				candidate.OutputTokens = result.Usage.OutputTokens
+				candidate.Cost = result.Cost
+				recordUsage(task, result)
+			}
+			candidates[i] = candidate
+			done++
+			s.updateProgress(task, 30+60*done/n)
+		}(i, client)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	// 审核各候选，被拒绝的候选视为失败，不能被选中
+	rejected := false
+	for _, candidate := range candidates {
+		if candidate.Error != "" {
+			continue
+		}
+		text, err := s.moderate(task, candidate.Text)
+		if err != nil {
+			candidate.Text = ""
+			candidate.Error = err.Error()
+			rejected = true
+			continue
+		}
+		candidate.Text = text
+	}
+
+	var chosen *model.AITaskCandidate
+	for _, candidate := range candidates {
+		if candidate.Error == "" {
+			chosen = candidate
+			break
+		}
+	}
+	if chosen == nil && rejected {
+		return "", fmt.Errorf("all %d candidates failed: %w", n, ErrModerationRejected)
+	}
+	if chosen == nil {
+		return "", fmt.Errorf("all %d candidates failed: %s", n, candidates[0].Error)
+	}
+	chosen.Chosen = true
+	// 任务的提供商和模型记录为选中候选的值
+	task.Provider = chosen.Provider
+	task.Model = chosen.Model
+
+	if err := s.candidateRepo.ReplaceByTaskID(task.ID, candidates); err != nil {
+		return "", err
+	}
+	return chosen.Text, nil
+}
+
+// ChooseCandidate 选择候选结果，选中的内容写入任务结果供应用到章节
+func (s *aiService) ChooseCandidate(userID, taskID, candidateID uint) (*dto.TaskStatusResponse, error) {
+	task, err := s.findOwnedTask(userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	// 未完成的任务可能仍在生成或被重新执行，选择的结果会被覆盖
+	if task.Status != model.AITaskStatusCompleted {
+		return nil, ErrAITaskNotCompleted
+	}
+
+	candidate, err := s.candidateRepo.FindByID(candidateID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAITaskCandidateNotFound) {
+			return nil, ErrAITaskCandidateNotFound
+		}
+		return nil, err
+	}
+	if candidate.TaskID != task.ID {
+		return nil, ErrAITaskCandidateNotFound
+	}
+	if candidate.Error != "" {
+		return nil, ErrAITaskCandidateFailed
+	}
+
+	if err := s.candidateRepo.Choose(candidate); err != nil {
+		return nil, err
+	}
+	return s.GetTaskStatus(userID, taskID)
+}
+
+// toCandidates 转换为候选结果响应
+func toCandidates(candidates []model.AITaskCandidate) []dto.AICandidate {
+	items := make([]dto.AICandidate, len(candidates))
+	for i, c := range candidates {
+		items[i] = dto.AICandidate{
+			CandidateID: c.ID,
+			Index:       c.Index,
+			Provider:    c.Provider,
+			Model:       c.Model,
+			Text:        c.Text,
+			Error:       c.Error,
+			Chosen:      c.Chosen,
+		}
+		if c.InputTokens > 0 || c.OutputTokens > 0 {
+			items[i].Usage = &dto.AIUsage{
+				InputTokens:  c.InputTokens,
+				OutputTokens: c.OutputTokens,
+				Cost:         c.Cost,
+			}
+		}
+	}
+	return items
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+)
+
+const (
+	defaultCharacterCount = 1
+	// characterTokensPerItem 每个角色设定预留的输出token数
+	characterTokensPerItem = 800
+)
+
+// GenerateCharacters AI角色生成
+func (s *aiService) GenerateCharacters(userID uint, req *dto.CharacterGenerateRequest) (*dto.AITaskResponse, error) {
+	// 验证作品权限
+	if err := s.validateWorkOwnership(userID, req.WorkID); err != nil {
+		return nil, err
+	}
+
+	// 创建任务并加入队列
+	return s.createTask(userID, req.WorkID, model.AITaskTypeCharacter, req, 20)
+}
+
+// processCharacterTask 处理角色生成任务：按作品简介、题材和已有角色生成角色设定，Save时创建为角色
+func (s *aiService) processCharacterTask(ctx context.Context, task *model.AITask, req *dto.CharacterGenerateRequest) {
+	s.updateProgress(task, 10)
+
+	work, err := s.workRepo.FindByID(req.WorkID)
+	if err != nil {
+		s.failTask(task, ErrWorkNotFound)
+		return
+	}
+	characters, err := s.characterRepo.FindByWorkID(work.ID)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	count := req.Count
+	if count <= 0 {
+		count = defaultCharacterCount
+	}
+	data := &promptData{
+		Work:       work,
+		Req:        req,
+		Characters: characters,
+	}
+
+	// 调用AI生成，输出不合法或与已有角色重名时重试
+	s.updateProgress(task, 30)
+	var result *dto.CharacterGenerateResult
+	err = s.generateJSON(ctx, task, data, 1024+count*characterTokensPerItem, func(output string) error {
+		var err error
+		result, err = parseGeneratedCharacters(output, count, req.Role, characters)
+		return err
+	})
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	if req.Save {
+		// 任务在生成期间被取消时不再创建角色
+		if err := ctx.Err(); err != nil {
+			s.failTask(task, err)
+			return
+		}
+		// 创建角色前先审核（完成任务时不再重复审核），未通过审核的内容不会写入角色
+		output, _ := json.Marshal(result)
+		moderated, err := s.moderate(task, string(output))
+		if err != nil {
+			s.failTask(task, err)
+			return
+		}
+		if moderated != string(output) {
+			if err := json.Unmarshal([]byte(moderated), result); err != nil {
+				s.failTask(task, err)
+				return
+			}
+		}
+
+		s.updateProgress(task, 90)
+		for i := range result.Characters {
+			c := &result.Characters[i]
+			created, err := s.characterService.Create(task.UserID, work.ID, &dto.CreateCharacterRequest{
+				Name:        c.Name,
+				Role:        c.Role,
+				Description: characterDescription(dto.SnowflakeCharacter{Description: c.Description, Motivation: c.Motivation}),
+			})
+			if err != nil {
+				s.failTask(task, fmt.Errorf("failed to save character %s: %w", c.Name, err))
+				return
+			}
+			c.CharacterID = created.CharacterID
+		}
+	}
+
+	output, _ := json.Marshal(result)
+	if req.Save {
+		// 保存前已审核过，不再重复审核
+		s.completeModeratedTask(task, string(output))
+		return
+	}
+	s.completeTask(task, string(output))
+}
+
+// parseGeneratedCharacters 解析并校验生成的角色：数量与要求一致，名字不能为空或与已有角色重复，
+// 指定了角色类型时统一使用该类型
+func parseGeneratedCharacters(output string, count int, role string, existing []model.Character) (*dto.CharacterGenerateResult, error) {
+	var result dto.CharacterGenerateResult
+	if err := decodeJSONOutput(output, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Characters) != count {
+		return nil, fmt.Errorf("%w: expected %d characters, got %d", ErrInvalidModelOutput, count, len(result.Characters))
+	}
+
+	names := make(map[string]bool, len(existing)+count)
+	for _, c := range existing {
+		names[c.Name] = true
+	}
+	for i := range result.Characters {
+		c := &result.Characters[i]
+		c.Name = strings.TrimSpace(c.Name)
+		c.Description = strings.TrimSpace(c.Description)
+		c.Motivation = strings.TrimSpace(c.Motivation)
+		c.CharacterID = 0
+		if c.Name == "" || utf8.RuneCountInString(c.Name) > 100 {
+			return nil, fmt.Errorf("%w: character %d has no valid name", ErrInvalidModelOutput, i+1)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("%w: character name %s already exists", ErrInvalidModelOutput, c.Name)
+		}
+		names[c.Name] = true
+		if c.Description == "" {
+			return nil, fmt.Errorf("%w: character %s has no description", ErrInvalidModelOutput, c.Name)
+		}
+
+		if role != "" {
+			c.Role = role
+		}
+		c.Role = strings.TrimSpace(c.Role)
+		if _, ok := roleLabels[model.CharacterRole(c.Role)]; !ok {
+			return nil, fmt.Errorf("%w: character %s has invalid role %q", ErrInvalidModelOutput, c.Name, c.Role)
+		}
+	}
+	return &result, nil
+}
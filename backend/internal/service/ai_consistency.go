@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+)
+
+const (
+	defaultConsistencyChunkChars = 40000
+	defaultConsistencyMaxTokens  = 4096
+	// chapterMarker 一致性检查时每章开头的标记，需与consistency_check模板保持一致
+	chapterMarker = "【章节"
+)
+
+// 一致性问题类型
+const (
+	ConsistencyTypeCharacter  = "character"
+	ConsistencyTypeTimeline   = "timeline"
+	ConsistencyTypePlotThread = "plot_thread"
+	ConsistencyTypeOther      = "other"
+)
+
+var (
+	consistencyTypes = map[string]bool{
+		ConsistencyTypeCharacter:  true,
+		ConsistencyTypeTimeline:   true,
+		ConsistencyTypePlotThread: true,
+		ConsistencyTypeOther:      true,
+	}
+	consistencySeverities = map[string]bool{"high": true, "medium": true, "low": true}
+)
+
+// consistencyOutput 模型输出的检查结果
+type consistencyOutput struct {
+	Findings []struct {
+		Type        string `json:"type"`
+		Severity    string `json:"severity"`
+		Description string `json:"description"`
+		Excerpts    []struct {
+			ChapterID looseID `json:"chapterId"`
+			Quote     string  `json:"quote"`
+		} `json:"excerpts"`
+	} `json:"findings"`
+}
+
+// CheckConsistency 跨章节一致性检查
+func (s *aiService) CheckConsistency(userID uint, req *dto.ConsistencyCheckRequest) (*dto.AITaskResponse, error) {
+	// 验证作品权限
+	if err := s.validateWorkOwnership(userID, req.WorkID); err != nil {
+		return nil, err
+	}
+	if count, err := s.chapterRepo.CountByWorkID(req.WorkID); err != nil {
+		return nil, err
+	} else if count == 0 {
+		return nil, ErrEmptyWork
+	}
+
+	// 创建任务并加入队列
+	return s.createTask(userID, req.WorkID, model.AITaskTypeConsistencyCheck, req, 90)
+}
+
+// processConsistencyCheckTask 处理一致性检查任务：章节连同角色设定交给模型检查，
+// 篇幅超出单次检查上限时分段检查（跨分段的矛盾只能借助角色设定和上一段结尾发现）
+func (s *aiService) processConsistencyCheckTask(ctx context.Context, task *model.AITask, req *dto.ConsistencyCheckRequest) {
+	s.updateProgress(task, 5)
+
+	work, err := s.workRepo.FindByID(req.WorkID)
+	if err != nil {
+		s.failTask(task, ErrWorkNotFound)
+		return
+	}
+	chapters, err := s.chapterRepo.FindByWorkID(work.ID)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+	characters, err := s.characterRepo.FindByWorkID(work.ID)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	// 章节标题前加上ID标记，模型据此标注问题所在章节
+	selected := make(map[uint]bool, len(req.ChapterIDs))
+	for _, id := range req.ChapterIDs {
+		selected[id] = true
+	}
+	texts := make(map[uint]string)
+	var order []uint
+	var labelled []model.Chapter
+	for _, ch := range chapters {
+		if len(selected) > 0 && !selected[ch.ID] {
+			continue
+		}
+		text := htmlToText(ch.Content)
+		if text == "" {
+			continue
+		}
+		texts[ch.ID] = text
+		order = append(order, ch.ID)
+		ch.Title = fmt.Sprintf("%s%d】%s", chapterMarker, ch.ID, ch.Title)
+		labelled = append(labelled, ch)
+	}
+
+	parts := splitManuscript(labelled, s.consistencyChunkChars())
+	if len(parts) == 0 {
+		s.failTask(task, ErrEmptyWork)
+		return
+	}
+
+	prompts := make([]string, len(parts))
+	for i, part := range parts {
+		prompts[i], err = s.renderPrompt(task, string(task.Type), &promptData{
+			Work:       work,
+			Req:        req,
+			Characters: characters,
+			Part:       part,
+		})
+		if err != nil {
+			s.failTask(task, err)
+			return
+		}
+	}
+
+	s.updateProgress(task, 10)
+	outputs, err := s.generateParts(ctx, task, prompts, s.consistencyMaxTokens(), 10, 95)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	report := &dto.ConsistencyReport{
+		CheckedChapters: len(texts),
+		Findings:        []dto.ConsistencyFinding{},
+	}
+	for i, output := range outputs {
+		var parsed consistencyOutput
+		if err := decodeJSONOutput(output, &parsed); err != nil {
+			s.failTask(task, fmt.Errorf("part %d/%d: %w", i+1, len(outputs), err))
+			return
+		}
+		for _, f := range parsed.Findings {
+			finding := dto.ConsistencyFinding{
+				Type:        normalizeOption(f.Type, consistencyTypes, ConsistencyTypeOther),
+				Severity:    normalizeOption(f.Severity, consistencySeverities, "medium"),
+				Description: strings.TrimSpace(f.Description),
+			}
+			seen := make(map[uint]bool)
+			for _, e := range f.Excerpts {
+				excerpt, ok := locateExcerpt(uint(e.ChapterID), strings.TrimSpace(e.Quote), texts, order)
+				if !ok {
+					continue
+				}
+				finding.Excerpts = append(finding.Excerpts, excerpt)
+				if !seen[excerpt.ChapterID] {
+					seen[excerpt.ChapterID] = true
+					finding.ChapterIDs = append(finding.ChapterIDs, excerpt.ChapterID)
+				}
+			}
+			// 没有说明或无法对应到所检查章节的问题不予采纳
+			if finding.Description == "" || len(finding.Excerpts) == 0 {
+				continue
+			}
+			report.Findings = append(report.Findings, finding)
+		}
+	}
+
+	result, _ := json.Marshal(report)
+	s.completeTask(task, string(result))
+}
+
+// locateExcerpt 核对引文所在章节：引文不在模型标注的章节中时，改为实际包含该引文的章节；
+// 都找不到时保留标注的章节并标记为未核实
+func locateExcerpt(chapterID uint, quote string, texts map[uint]string, order []uint) (dto.ConsistencyExcerpt, bool) {
+	if quote == "" {
+		return dto.ConsistencyExcerpt{}, false
+	}
+	needle := stripSpaces(quote)
+	if text, ok := texts[chapterID]; ok && strings.Contains(stripSpaces(text), needle) {
+		return dto.ConsistencyExcerpt{ChapterID: chapterID, Quote: quote, Verified: true}, true
+	}
+	for _, id := range order {
+		if strings.Contains(stripSpaces(texts[id]), needle) {
+			return dto.ConsistencyExcerpt{ChapterID: id, Quote: quote, Verified: true}, true
+		}
+	}
+	if _, ok := texts[chapterID]; ok {
+		return dto.ConsistencyExcerpt{ChapterID: chapterID, Quote: quote}, true
+	}
+	return dto.ConsistencyExcerpt{}, false
+}
+
+// normalizeOption 将模型输出的枚举值规范为小写，不在可选范围内时使用默认值
+func normalizeOption(value string, options map[string]bool, fallback string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if options[value] {
+		return value
+	}
+	return fallback
+}
+
+// stripSpaces 去掉所有空白，用于忽略换行和缩进差异比较引文
+func stripSpaces(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// consistencyChunkChars 每次检查的最大原文字数
+func (s *aiService) consistencyChunkChars() int {
+	if s.cfg.AI.Consistency.ChunkChars > 0 {
+		return s.cfg.AI.Consistency.ChunkChars
+	}
+	return defaultConsistencyChunkChars
+}
+
+// consistencyMaxTokens 每段输出的最大token数
+func (s *aiService) consistencyMaxTokens() int {
+	if s.cfg.AI.Consistency.MaxTokens > 0 {
+		return s.cfg.AI.Consistency.MaxTokens
+	}
+	return defaultConsistencyMaxTokens
+}
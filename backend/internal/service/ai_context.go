@@ -0,0 +1,374 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+)
+
+const (
+	defaultContextMaxTokens = 1500
+	defaultRecentChapters   = 3
+	defaultExcerptChars     = 200
+	defaultPrecedingTokens  = 4000
+	// characterDescriptionChars 每个角色描述最多保留的字数
+	characterDescriptionChars = 120
+	// summaryExcerptChars 近期章节有摘要时每章最多保留的摘要字数
+	summaryExcerptChars = 300
+	// minContextLineTokens 预算剩余不足时不再截断写入半行
+	minContextLineTokens = 20
+)
+
+// contextOptions 带有作品设定开关的请求
+type contextOptions interface {
+	ContextEnabled() bool
+}
+
+// wholeWorkTaskTypes 以整部作品为输入的任务，模板中已包含作品信息和角色，只注入雪花写作法设定
+var wholeWorkTaskTypes = map[model.AITaskType]bool{
+	model.AITaskTypeNovelToScreenplay: true,
+	model.AITaskTypeScreenplayToNovel: true,
+	model.AITaskTypeConsistencyCheck:  true,
+}
+
+// roleLabels 角色类型的中文名称
+var roleLabels = map[model.CharacterRole]string{
+	model.CharacterRoleProtagonist: "主角",
+	model.CharacterRoleAntagonist:  "反派",
+	model.CharacterRoleSupporting:  "配角",
+}
+
+// bibleSection 作品设定中的一节，按优先级排列，超出预算时丢弃靠后的行
+type bibleSection struct {
+	title string
+	lines []string
+}
+
+// contextEnabled 任务是否注入作品设定：配置总开关开启且请求没有关闭
+func (s *aiService) contextEnabled(req interface{}) bool {
+	if !s.cfg.AI.Context.Enabled {
+		return false
+	}
+	if opts, ok := req.(contextOptions); ok {
+		return opts.ContextEnabled()
+	}
+	return true
+}
+
+// buildStoryBible 在token预算内组装作品设定：作品简介 > 全书梗概 > 雪花写作法设定 > 角色 > 近期章节
+func (s *aiService) buildStoryBible(task *model.AITask, data *promptData) (string, error) {
+	work := data.Work
+	full := !wholeWorkTaskTypes[task.Type]
+
+	var sections []bibleSection
+	if full {
+		sections = append(sections,
+			workSection(work),
+			bibleSection{title: "全书梗概", lines: splitLines(work.Synopsis)},
+		)
+	}
+	var profiles []map[string]interface{}
+	if snowflake := work.Metadata.Snowflake; snowflake != nil {
+		sections = append(sections,
+			bibleSection{title: "核心概括", lines: splitLines(snowflake.Step1)},
+			bibleSection{title: "故事梗概", lines: splitLines(snowflake.Step2)},
+		)
+		profiles = snowflake.Step3
+	}
+	if full {
+		characters, err := s.characterRepo.FindByWorkID(work.ID)
+		if err != nil {
+			return "", err
+		}
+		sections = append(sections, characterSection(characters, profiles))
+
+		chapters, err := s.recentChapters(work.ID, data)
+		if err != nil {
+			return "", err
+		}
+		sections = append(sections, recentChapterSection(chapters, s.contextRecentChapters(), s.contextExcerptChars()))
+	}
+
+	return fitSections(sections, s.contextMaxTokens()), nil
+}
+
+// workSection 作品基本信息
+func workSection(work *model.Work) bibleSection {
+	section := bibleSection{title: "作品信息"}
+	section.lines = append(section.lines, "作品：《"+work.Title+"》")
+	if work.Type == model.WorkTypeScreenplay {
+		section.lines = append(section.lines, "体裁：剧本")
+	} else {
+		section.lines = append(section.lines, "体裁：小说")
+	}
+	if work.Genre != "" {
+		section.lines = append(section.lines, "题材："+work.Genre)
+	}
+	if topic := strings.TrimSpace(work.Topic); topic != "" {
+		section.lines = append(section.lines, "简介："+topic)
+	}
+	return section
+}
+
+// characterSection 角色列表，雪花写作法中设定但尚未建立角色记录的角色排在后面
+func characterSection(characters []model.Character, profiles []map[string]interface{}) bibleSection {
+	section := bibleSection{title: "主要角色"}
+	names := make(map[string]bool, len(characters))
+
+	// 主角和反派优先
+	sorted := append([]model.Character(nil), characters...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rolePriority(sorted[i].Role) < rolePriority(sorted[j].Role)
+	})
+	for _, c := range sorted {
+		names[c.Name] = true
+		line := "- " + c.Name
+		if label, ok := roleLabels[c.Role]; ok {
+			line += "（" + label + "）"
+		}
+		if desc := strings.TrimSpace(c.Description); desc != "" {
+			line += "：" + truncateRunes(desc, characterDescriptionChars)
+		}
+		section.lines = append(section.lines, line)
+	}
+
+	for _, profile := range profiles {
+		name := profileString(profile, "name")
+		if name == "" || names[name] {
+			continue
+		}
+		names[name] = true
+		line := "- " + name
+		if role := profileString(profile, "role"); role != "" {
+			if label, ok := roleLabels[model.CharacterRole(role)]; ok {
+				role = label
+			}
+			line += "（" + role + "）"
+		}
+		if desc := profileString(profile, "description"); desc != "" {
+			line += "：" + truncateRunes(desc, characterDescriptionChars)
+		}
+		section.lines = append(section.lines, line)
+	}
+	return section
+}
+
+// recentChapterSection 最近若干章的摘要，由近及远排列；还没有生成摘要的章节使用结尾摘录
+func recentChapterSection(chapters []model.Chapter, count, excerptChars int) bibleSection {
+	section := bibleSection{title: "近期章节（由近及远）"}
+	for i := len(chapters) - 1; i >= 0 && len(section.lines) < count; i-- {
+		if summary := strings.TrimSpace(chapters[i].Summary); summary != "" {
+			summary = strings.Join(strings.Fields(truncateRunes(summary, summaryExcerptChars)), " ")
+			section.lines = append(section.lines, fmt.Sprintf("- %s：%s", chapters[i].Title, summary))
+			continue
+		}
+		text := htmlToText(chapters[i].Content)
+		if text == "" {
+			continue
+		}
+		excerpt := strings.Join(strings.Fields(tailRunes(text, excerptChars)), " ")
+		section.lines = append(section.lines, fmt.Sprintf("- %s：……%s", chapters[i].Title, excerpt))
+	}
+	return section
+}
+
+// recentChapters 读取续写位置之前的近期章节，只有没有摘要的章节才读取正文用于截取结尾
+func (s *aiService) recentChapters(workID uint, data *promptData) ([]model.Chapter, error) {
+	limit := s.contextRecentChapters()
+	before := 0
+	// 上一章结尾已作为前文提供，多取一章后去掉
+	skipPrevious := false
+	if data.Chapter != nil {
+		before = data.Chapter.OrderNum
+		skipPrevious = data.Part != nil && data.Part.Previous != ""
+	}
+	if skipPrevious {
+		limit++
+	}
+
+	chapters, err := s.chapterRepo.FindRecentSummaries(workID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	if skipPrevious && len(chapters) > 0 {
+		chapters = chapters[:len(chapters)-1]
+	}
+
+	for i := range chapters {
+		if strings.TrimSpace(chapters[i].Summary) != "" {
+			continue
+		}
+		chapter, err := s.chapterRepo.FindByID(chapters[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		chapters[i].Content = chapter.Content
+	}
+	return chapters, nil
+}
+
+// loadPreceding 从章节读取续写位置之前的内容，在token预算内保留靠近光标的部分，
+// 前文不足预算时附带上一章结尾（最多占预算的四分之一）
+func (s *aiService) loadPreceding(req *dto.ContinueRequest) (*model.Chapter, *convertPart, error) {
+	chapter, err := s.findWorkChapter(req.WorkID, req.ChapterID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	runes := []rune(plainText(chapter.Content))
+	cursor := len(runes)
+	// 排队期间章节变短时从末尾续写
+	if req.Cursor != nil && *req.Cursor < cursor {
+		cursor = *req.Cursor
+	}
+
+	budget := s.contextPrecedingTokens()
+	part := &convertPart{
+		ChapterTitles: chapter.Title,
+		Text:          tailTokens(strings.TrimSpace(string(runes[:cursor])), budget),
+	}
+	if rest := budget - estimateTokens(part.Text); rest > 0 {
+		if prev, err := s.chapterRepo.FindPrevious(chapter.WorkID, chapter.OrderNum); err == nil {
+			part.Previous = tailTokens(htmlToText(prev.Content), min(rest, budget/4))
+		}
+	}
+	return chapter, part, nil
+}
+
+// validateContinueChapter 验证续写章节属于作品且光标没有超出章节内容
+func (s *aiService) validateContinueChapter(req *dto.ContinueRequest) error {
+	if req.ChapterID == 0 {
+		return nil
+	}
+	chapter, err := s.findWorkChapter(req.WorkID, req.ChapterID)
+	if err != nil {
+		return err
+	}
+	if req.Cursor != nil && *req.Cursor > utf8.RuneCountInString(plainText(chapter.Content)) {
+		return ErrInvalidTextRange
+	}
+	return nil
+}
+
+// findWorkChapter 查找作品中的章节
+func (s *aiService) findWorkChapter(workID, chapterID uint) (*model.Chapter, error) {
+	chapter, err := s.chapterRepo.FindByID(chapterID)
+	if err != nil || chapter.WorkID != workID {
+		return nil, ErrChapterNotFound
+	}
+	return chapter, nil
+}
+
+// fitSections 按优先级写入各节，超出预算时截断当前行并丢弃其余内容
+func fitSections(sections []bibleSection, budget int) string {
+	var b strings.Builder
+	used := 0
+	for _, section := range sections {
+		if len(section.lines) == 0 {
+			continue
+		}
+		header := "【" + section.title + "】"
+		cost := estimateTokens(header) + 1
+		if used+cost+minContextLineTokens > budget {
+			break
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(header)
+		used += cost
+
+		for _, line := range section.lines {
+			cost := estimateTokens(line) + 1
+			if used+cost > budget {
+				if rest := budget - used - 1; rest >= minContextLineTokens {
+					b.WriteString("\n")
+					b.WriteString(truncateTokens(line, rest))
+				}
+				return b.String()
+			}
+			b.WriteString("\n")
+			b.WriteString(line)
+			used += cost
+		}
+	}
+	return b.String()
+}
+
+// rolePriority 角色排序权重
+func rolePriority(role model.CharacterRole) int {
+	switch role {
+	case model.CharacterRoleProtagonist:
+		return 0
+	case model.CharacterRoleAntagonist:
+		return 1
+	case model.CharacterRoleSupporting:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// profileString 读取雪花写作法角色设定中的字符串字段
+func profileString(profile map[string]interface{}, key string) string {
+	if v, ok := profile[key].(string); ok {
+		return strings.TrimSpace(v)
+	}
+	return ""
+}
+
+// splitLines 按行切分并去掉空行
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// truncateRunes 截取前n个字，截断时以省略号结尾
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// contextMaxTokens 作品设定的token预算
+func (s *aiService) contextMaxTokens() int {
+	if s.cfg.AI.Context.MaxTokens > 0 {
+		return s.cfg.AI.Context.MaxTokens
+	}
+	return defaultContextMaxTokens
+}
+
+// contextRecentChapters 附带的近期章节数
+func (s *aiService) contextRecentChapters() int {
+	if s.cfg.AI.Context.RecentChapters > 0 {
+		return s.cfg.AI.Context.RecentChapters
+	}
+	return defaultRecentChapters
+}
+
+// contextPrecedingTokens 续写时读取的前文token上限
+func (s *aiService) contextPrecedingTokens() int {
+	if s.cfg.AI.Context.PrecedingTokens > 0 {
+		return s.cfg.AI.Context.PrecedingTokens
+	}
+	return defaultPrecedingTokens
+}
+
+// contextExcerptChars 每个近期章节的摘录字数
+func (s *aiService) contextExcerptChars() int {
+	if s.cfg.AI.Context.ExcerptChars > 0 {
+		return s.cfg.AI.Context.ExcerptChars
+	}
+	return defaultExcerptChars
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFitSections(t *testing.T) {
+	long := strings.Repeat("字", 30)
+	tests := []struct {
+		name     string
+		sections []bibleSection
+		budget   int
+		want     string
+	}{
+		{
+			name: "all sections fit and empty sections are skipped",
+			sections: []bibleSection{
+				{title: "甲", lines: []string{"一二", "三四"}},
+				{title: "乙"},
+				{title: "丙", lines: []string{"五"}},
+			},
+			budget: 1000,
+			want:   "【甲】\n一二\n三四\n【丙】\n五",
+		},
+		{
+			name:     "budget too small for any section",
+			sections: []bibleSection{{title: "甲", lines: []string{"一二"}}},
+			budget:   10,
+			want:     "",
+		},
+		{
+			name: "later section dropped when its header does not fit",
+			sections: []bibleSection{
+				{title: "甲", lines: []string{"一二三四五"}},
+				{title: "乙", lines: []string{"六"}},
+			},
+			budget: 30,
+			want:   "【甲】\n一二三四五",
+		},
+		{
+			name: "line truncated to remaining budget and rest dropped",
+			sections: []bibleSection{
+				{title: "甲", lines: []string{"一二三四五", long, "六"}},
+				{title: "乙", lines: []string{"七"}},
+			},
+			budget: 40,
+			want:   "【甲】\n一二三四五\n" + strings.Repeat("字", 28) + "…",
+		},
+		{
+			name:     "remaining budget too small for a truncated line",
+			sections: []bibleSection{{title: "甲", lines: []string{"一二三四五", long}}},
+			budget:   30,
+			want:     "【甲】\n一二三四五",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fitSections(tt.sections, tt.budget); got != tt.want {
+				t.Errorf("fitSections() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/pkg/ai"
+)
+
+// ErrEmptyWork 作品没有可转换的章节内容
+var ErrEmptyWork = errors.New("work has no chapter content")
+
+const (
+	defaultChunkChars         = 12000
+	defaultConvertConcurrency = 2
+	defaultConvertMaxTokens   = 8192
+	// previousTailChars 提供给下一段用于衔接的上一段原文结尾字数
+	previousTailChars = 300
+	// sceneMarker 模板要求模型在每个场景标题前输出的标记，需与novel_to_screenplay模板保持一致
+	sceneMarker = "【场景】"
+)
+
+// convertPart 整部作品转换时的一个分段
+type convertPart struct {
+	Index         int    // 从1开始
+	Total         int    // 分段总数
+	ChapterTitles string // 本段包含的章节标题
+	Text          string // 本段原文
+	Previous      string // 上一段原文结尾，仅用于衔接
+	Scenes        int    // 本段分配的场景数，0表示不限制
+	Minutes       int    // 本段分配的时长（分钟），0表示不限制
+	Words         int    // 本段改写后的目标字数，0表示不限制
+
+	length int
+	titles []string
+}
+
+// processNovelToScreenplayTask 处理小说转剧本任务：按章节分段转换为场景，再拼接并统一编号
+func (s *aiService) processNovelToScreenplayTask(ctx context.Context, task *model.AITask, req *dto.NovelToScreenplayRequest) {
+	s.updateProgress(task, 5)
+
+	work, err := s.workRepo.FindByID(req.WorkID)
+	if err != nil {
+		s.failTask(task, ErrWorkNotFound)
+		return
+	}
+	chapters, err := s.chapterRepo.FindByWorkID(work.ID)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+	characters, err := s.characterRepo.FindByWorkID(work.ID)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	parts := splitManuscript(chapters, s.convertChunkChars())
+	if len(parts) == 0 {
+		s.failTask(task, ErrEmptyWork)
+		return
+	}
+
+	// 按原文长度分配场景数和时长
+	weights := make([]int, len(parts))
+	for i, part := range parts {
+		weights[i] = part.length
+	}
+	scenes := allocate(req.NumScenes, weights)
+	minutes := allocate(req.TargetDuration, weights)
+
+	// 所有分段共享作品信息和角色设定，保证角色名和设定一致
+	prompts := make([]string, len(parts))
+	for i, part := range parts {
+		part.Scenes = scenes[i]
+		part.Minutes = minutes[i]
+		prompts[i], err = s.renderPrompt(task, string(task.Type), &promptData{
+			Work:       work,
+			Req:        req,
+			Characters: characters,
+			Part:       part,
+		})
+		if err != nil {
+			s.failTask(task, err)
+			return
+		}
+	}
+
+	s.updateProgress(task, 10)
+	outputs, err := s.generateParts(ctx, task, prompts, s.convertMaxTokens(), 10, 95)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	s.completeTask(task, stitchScenes(outputs))
+}
+
+// generateParts 并发生成各分段，每完成一段更新一次进度（在from到to之间），任一分段失败时取消其余分段
+func (s *aiService) generateParts(ctx context.Context, task *model.AITask, prompts []string, maxTokens, from, to int) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client := s.clientFor(task.Type)
+	outputs := make([]string, len(prompts))
+	sem := make(chan struct{}, s.convertConcurrency())
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		done     int
+		firstErr error
+	)
+	for i, p := range prompts {
+		wg.Add(1)
+		go func(i int, p string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			result, err := s.generateCached(ctx, task, client, p, maxTokens, 0, func() (*ai.Result, error) {
+				return client.Generate(ctx, p, maxTokens)
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("part %d/%d: %w", i+1, len(prompts), err)
+					cancel()
+				}
+				return
+			}
+			outputs[i] = result.Text
+			recordUsage(task, result)
+			done++
+			s.updateProgress(task, from+(to-from)*done/len(prompts))
+		}(i, p)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+// splitManuscript 按字数将章节切分为若干段，章节尽量不拆开，超长章节按段落拆分
+func splitManuscript(chapters []model.Chapter, maxChars int) []*convertPart {
+	var parts []*convertPart
+	var cur *convertPart
+	for _, ch := range chapters {
+		content := htmlToText(ch.Content)
+		if content == "" {
+			continue
+		}
+		for _, piece := range splitText(ch.Title+"\n"+content, maxChars) {
+			n := utf8.RuneCountInString(piece)
+			if cur != nil && cur.length+n > maxChars {
+				parts = append(parts, cur)
+				cur = nil
+			}
+			if cur == nil {
+				cur = &convertPart{}
+			}
+			if cur.Text != "" {
+				cur.Text += "\n\n"
+			}
+			cur.Text += piece
+			cur.length += n
+			if len(cur.titles) == 0 || cur.titles[len(cur.titles)-1] != ch.Title {
+				cur.titles = append(cur.titles, ch.Title)
+			}
+		}
+	}
+	if cur != nil {
+		parts = append(parts, cur)
+	}
+
+	for i, part := range parts {
+		part.Index = i + 1
+		part.Total = len(parts)
+		part.ChapterTitles = strings.Join(part.titles, "、")
+		if i > 0 {
+			part.Previous = tailRunes(parts[i-1].Text, previousTailChars)
+		}
+	}
+	return parts
+}
+
+// splitText 将文本按段落切分为不超过maxChars的片段，单个段落过长时按字数硬切
+func splitText(text string, maxChars int) []string {
+	if utf8.RuneCountInString(text) <= maxChars {
+		return []string{text}
+	}
+
+	var pieces []string
+	var cur strings.Builder
+	curLen := 0
+	for _, para := range strings.Split(text, "\n") {
+		for _, seg := range chunkRunes(para, maxChars) {
+			n := utf8.RuneCountInString(seg) + 1
+			if curLen > 0 && curLen+n > maxChars {
+				pieces = append(pieces, strings.TrimSpace(cur.String()))
+				cur.Reset()
+				curLen = 0
+			}
+			cur.WriteString(seg)
+			cur.WriteString("\n")
+			curLen += n
+		}
+	}
+	if curLen > 0 {
+		pieces = append(pieces, strings.TrimSpace(cur.String()))
+	}
+	return pieces
+}
+
+// chunkRunes 按字数硬切字符串
+func chunkRunes(s string, size int) []string {
+	runes := []rune(s)
+	if len(runes) <= size {
+		return []string{s}
+	}
+	var chunks []string
+	for len(runes) > size {
+		chunks = append(chunks, string(runes[:size]))
+		runes = runes[size:]
+	}
+	return append(chunks, string(runes))
+}
+
+// tailRunes 取字符串末尾n个字
+func tailRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[len(runes)-n:])
+}
+
+// allocate 按权重将total分配到各段（最大余数法），total大于0时每段至少分到1（模板把0视为不限制），
+// total不足段数时每段为1，合计会超过total
+func allocate(total int, weights []int) []int {
+	result := make([]int, len(weights))
+	if total <= 0 || len(weights) == 0 {
+		return result
+	}
+	if total <= len(weights) {
+		for i := range result {
+			result[i] = 1
+		}
+		return result
+	}
+
+	sum := 0
+	for _, w := range weights {
+		sum += w
+	}
+	if sum == 0 {
+		return result
+	}
+
+	type remainder struct {
+		index int
+		value int
+	}
+	assigned := 0
+	rems := make([]remainder, len(weights))
+	for i, w := range weights {
+		result[i] = total * w / sum
+		assigned += result[i]
+		rems[i] = remainder{index: i, value: total * w % sum}
+	}
+	for assigned < total {
+		best := 0
+		for i := range rems {
+			if rems[i].value > rems[best].value {
+				best = i
+			}
+		}
+		result[rems[best].index]++
+		rems[best].value = -1
+		assigned++
+	}
+
+	// 分到0的段从当前最多的段借1
+	for i := range result {
+		if result[i] > 0 {
+			continue
+		}
+		largest := 0
+		for j := range result {
+			if result[j] > result[largest] {
+				largest = j
+			}
+		}
+		result[largest]--
+		result[i] = 1
+	}
+	return result
+}
+
+// stitchScenes 按顺序拼接各段输出，以场景标记切分场景并重新统一编号
+func stitchScenes(outputs []string) string {
+	type scene struct {
+		heading string
+		body    []string
+	}
+	var prologue []string
+	var scenes []*scene
+
+	for _, output := range outputs {
+		for _, line := range strings.Split(output, "\n") {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, sceneMarker) {
+				heading := strings.TrimSpace(strings.TrimPrefix(trimmed, sceneMarker))
+				scenes = append(scenes, &scene{heading: heading})
+				continue
+			}
+			// 没有场景标记的内容归入上一个场景
+			if len(scenes) == 0 {
+				prologue = append(prologue, line)
+			} else {
+				last := scenes[len(scenes)-1]
+				last.body = append(last.body, line)
+			}
+		}
+	}
+
+	var b strings.Builder
+	if text := strings.TrimSpace(strings.Join(prologue, "\n")); text != "" {
+		b.WriteString(text)
+	}
+	for i, sc := range scenes {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "第%d场 %s", i+1, sc.heading)
+		if body := strings.TrimSpace(strings.Join(sc.body, "\n")); body != "" {
+			b.WriteString("\n")
+			b.WriteString(body)
+		}
+	}
+	return b.String()
+}
+
+// convertChunkChars 每段原文最大字数
+func (s *aiService) convertChunkChars() int {
+	if s.cfg.AI.Convert.ChunkChars > 0 {
+		return s.cfg.AI.Convert.ChunkChars
+	}
+	return defaultChunkChars
+}
+
+// convertConcurrency 同时转换的分段数
+func (s *aiService) convertConcurrency() int {
+	if s.cfg.AI.Convert.Concurrency > 0 {
+		return s.cfg.AI.Convert.Concurrency
+	}
+	return defaultConvertConcurrency
+}
+
+// convertMaxTokens 每段输出的最大token数
+func (s *aiService) convertMaxTokens() int {
+	if s.cfg.AI.Convert.MaxTokens > 0 {
+		return s.cfg.AI.Convert.MaxTokens
+	}
+	return defaultConvertMaxTokens
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jugo/backend/internal/model"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxChars int
+		want     []string
+	}{
+		{"short text", "aaa\nbbb", 10, []string{"aaa\nbbb"}},
+		{"split at paragraphs", "aaa\nbbb\nccc", 8, []string{"aaa\nbbb", "ccc"}},
+		{"counts runes", "一二三\n四五六", 4, []string{"一二三", "四五六"}},
+		{"hard cut long paragraph", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitText(tt.text, tt.maxChars); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitManuscript(t *testing.T) {
+	type part struct {
+		Index         int
+		Total         int
+		ChapterTitles string
+		Text          string
+		Previous      string
+	}
+	chapters := []model.Chapter{
+		{Title: "第一章", Content: "甲乙丙"},
+		{Title: "空章", Content: ""},
+		{Title: "第二章", Content: "丁戊"},
+	}
+	tests := []struct {
+		name     string
+		maxChars int
+		want     []part
+	}{
+		{
+			name:     "chapters fit in one part",
+			maxChars: 100,
+			want: []part{
+				{Index: 1, Total: 1, ChapterTitles: "第一章、第二章", Text: "第一章\n甲乙丙\n\n第二章\n丁戊"},
+			},
+		},
+		{
+			name:     "chapters split into parts",
+			maxChars: 8,
+			want: []part{
+				{Index: 1, Total: 2, ChapterTitles: "第一章", Text: "第一章\n甲乙丙"},
+				{Index: 2, Total: 2, ChapterTitles: "第二章", Text: "第二章\n丁戊", Previous: "第一章\n甲乙丙"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []part
+			for _, p := range splitManuscript(chapters, tt.maxChars) {
+				got = append(got, part{p.Index, p.Total, p.ChapterTitles, p.Text, p.Previous})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitManuscript() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := splitManuscript([]model.Chapter{{Title: "空章"}}, 100); len(got) != 0 {
+		t.Errorf("splitManuscript() with empty chapters = %d parts, want 0", len(got))
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int
+		weights []int
+		want    []int
+	}{
+		{"zero total", 0, []int{1, 2}, []int{0, 0}},
+		{"no weights", 5, []int{}, []int{}},
+		{"zero weights", 5, []int{0, 0}, []int{0, 0}},
+		{"even split", 10, []int{1, 1}, []int{5, 5}},
+		{"largest remainder", 10, []int{1, 2, 1}, []int{3, 5, 2}},
+		{"total less than parts", 2, []int{1, 1, 1}, []int{1, 1, 1}},
+		{"total equals parts", 3, []int{1, 5, 1}, []int{1, 1, 1}},
+		{"small part gets at least one", 4, []int{1, 10, 10}, []int{1, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allocate(tt.total, tt.weights)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allocate(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStitchScenes(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs []string
+		want    string
+	}{
+		{
+			name:    "renumbers scenes across parts",
+			outputs: []string{"【场景】INT. 客厅 - 夜\n他走进来。", "【场景】EXT. 街道 - 日\n她离开。"},
+			want:    "第1场 INT. 客厅 - 夜\n他走进来。\n\n第2场 EXT. 街道 - 日\n她离开。",
+		},
+		{
+			name:    "keeps text before first scene",
+			outputs: []string{"标题\n【场景】A\nx"},
+			want:    "标题\n\n第1场 A\nx",
+		},
+		{
+			name:    "unmarked text continues previous scene",
+			outputs: []string{"【场景】A\nx", "y"},
+			want:    "第1场 A\nx\ny",
+		},
+		{
+			name:    "scene without body",
+			outputs: []string{"【场景】A\n\n【场景】B\nb"},
+			want:    "第1场 A\n\n第2场 B\nb",
+		},
+		{
+			name:    "no scenes",
+			outputs: []string{"只有文字"},
+			want:    "只有文字",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stitchScenes(tt.outputs); got != tt.want {
+				t.Errorf("stitchScenes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
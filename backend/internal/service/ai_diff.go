@@ -0,0 +1,160 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jugo/backend/internal/dto"
+)
+
+// 差异片段类型
+const (
+	DiffOpEqual  = "equal"
+	DiffOpInsert = "insert"
+	DiffOpDelete = "delete"
+)
+
+// maxDiffCells 逐字比较的最大计算量（两段文本去掉公共首尾后的字数乘积），超出时按句子比较
+const maxDiffCells = 4000000
+
+// diffText 计算从a到b的差异，中文按字、英文和数字按词比较，相邻的同类片段会合并
+func diffText(a, b string) []dto.DiffSegment {
+	segments := diffTokens(tokenize(a, false), tokenize(b, false), true)
+	return mergeSegments(segments)
+}
+
+// diffTokens 去掉公共首尾后用最长公共子序列比较，计算量过大时先退化为按句子比较，仍过大时整体替换
+func diffTokens(ta, tb []string, fine bool) []dto.DiffSegment {
+	prefix := 0
+	for prefix < len(ta) && prefix < len(tb) && ta[prefix] == tb[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(ta)-prefix && suffix < len(tb)-prefix && ta[len(ta)-1-suffix] == tb[len(tb)-1-suffix] {
+		suffix++
+	}
+
+	var segments []dto.DiffSegment
+	segments = appendSegment(segments, DiffOpEqual, ta[:prefix])
+
+	midA, midB := ta[prefix:len(ta)-suffix], tb[prefix:len(tb)-suffix]
+	switch {
+	case len(midA) == 0 || len(midB) == 0 || len(midA)*len(midB) <= maxDiffCells:
+		segments = append(segments, lcsDiff(midA, midB)...)
+	case fine:
+		a, b := strings.Join(midA, ""), strings.Join(midB, "")
+		segments = append(segments, diffTokens(tokenize(a, true), tokenize(b, true), false)...)
+	default:
+		segments = appendSegment(segments, DiffOpDelete, midA)
+		segments = appendSegment(segments, DiffOpInsert, midB)
+	}
+
+	return appendSegment(segments, DiffOpEqual, ta[len(ta)-suffix:])
+}
+
+// lcsDiff 基于最长公共子序列的差异
+func lcsDiff(ta, tb []string) []dto.DiffSegment {
+	n, m := len(ta), len(tb)
+	// dp[i*(m+1)+j] 为ta[i:]与tb[j:]的最长公共子序列长度
+	dp := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if ta[i] == tb[j] {
+				dp[i*(m+1)+j] = dp[(i+1)*(m+1)+j+1] + 1
+			} else if dp[(i+1)*(m+1)+j] >= dp[i*(m+1)+j+1] {
+				dp[i*(m+1)+j] = dp[(i+1)*(m+1)+j]
+			} else {
+				dp[i*(m+1)+j] = dp[i*(m+1)+j+1]
+			}
+		}
+	}
+
+	var segments []dto.DiffSegment
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case ta[i] == tb[j]:
+			segments = appendSegment(segments, DiffOpEqual, ta[i:i+1])
+			i++
+			j++
+		case dp[(i+1)*(m+1)+j] >= dp[i*(m+1)+j+1]:
+			segments = appendSegment(segments, DiffOpDelete, ta[i:i+1])
+			i++
+		default:
+			segments = appendSegment(segments, DiffOpInsert, tb[j:j+1])
+			j++
+		}
+	}
+	segments = appendSegment(segments, DiffOpDelete, ta[i:])
+	return appendSegment(segments, DiffOpInsert, tb[j:])
+}
+
+// appendSegment 追加片段，与上一个片段类型相同时合并
+func appendSegment(segments []dto.DiffSegment, op string, tokens []string) []dto.DiffSegment {
+	if len(tokens) == 0 {
+		return segments
+	}
+	text := strings.Join(tokens, "")
+	if n := len(segments); n > 0 && segments[n-1].Op == op {
+		segments[n-1].Text += text
+		return segments
+	}
+	return append(segments, dto.DiffSegment{Op: op, Text: text})
+}
+
+// mergeSegments 合并相邻的同类片段，并将被相同文本隔开的删除和插入整理为先删除后插入
+func mergeSegments(segments []dto.DiffSegment) []dto.DiffSegment {
+	merged := make([]dto.DiffSegment, 0, len(segments))
+	for _, seg := range segments {
+		n := len(merged)
+		switch {
+		case n > 0 && merged[n-1].Op == seg.Op:
+			merged[n-1].Text += seg.Text
+		case n > 0 && seg.Op == DiffOpDelete && merged[n-1].Op == DiffOpInsert:
+			// 插入后紧跟删除时交换顺序，便于展示
+			if n > 1 && merged[n-2].Op == DiffOpDelete {
+				merged[n-2].Text += seg.Text
+			} else {
+				merged = append(merged[:n-1], seg, merged[n-1])
+			}
+		default:
+			merged = append(merged, seg)
+		}
+	}
+	return merged
+}
+
+// tokenize 切分比较单位：sentences为false时中文按字、连续的字母数字为一个词，
+// 为true时按句末标点和换行切分句子
+func tokenize(text string, sentences bool) []string {
+	var tokens []string
+	start := 0
+	for i, r := range text {
+		if sentences {
+			if strings.ContainsRune("。！？!?；;\n", r) {
+				end := i + len(string(r))
+				tokens = append(tokens, text[start:end])
+				start = end
+			}
+			continue
+		}
+		if isWordRune(r) {
+			continue
+		}
+		if start < i {
+			tokens = append(tokens, text[start:i])
+		}
+		end := i + len(string(r))
+		tokens = append(tokens, text[i:end])
+		start = end
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// isWordRune 是否为英文单词或数字的组成字符（中文等其他文字逐字比较）
+func isWordRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '\'')
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+)
+
+// resultPreviewChars 任务列表中结果预览的字数
+const resultPreviewChars = 200
+
+// ErrInvalidAITaskQuery 任务查询条件无效
+var ErrInvalidAITaskQuery = errors.New("invalid AI task query")
+
+// ListTasks 按作品、类型、状态和创建日期分页查询用户的AI任务历史
+func (s *aiService) ListTasks(userID uint, params *dto.AITaskQueryParams) (*dto.AITaskListResponse, error) {
+	if params.Type != "" && !isTaskType(params.Type) {
+		return nil, ErrInvalidAITaskQuery
+	}
+
+	var from, to time.Time
+	if params.From != "" {
+		f, err := time.ParseInLocation(usageDateLayout, params.From, time.Local)
+		if err != nil {
+			return nil, ErrInvalidAITaskQuery
+		}
+		from = f
+	}
+	if params.To != "" {
+		t, err := time.ParseInLocation(usageDateLayout, params.To, time.Local)
+		if err != nil {
+			return nil, ErrInvalidAITaskQuery
+		}
+		// 包含结束日期当天
+		to = t.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, ErrInvalidAITaskQuery
+	}
+
+	tasks, total, err := s.aiTaskRepo.FindByUserID(userID, params, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.AITaskListItem, len(tasks))
+	for i, task := range tasks {
+		items[i] = dto.AITaskListItem{
+			TaskID:        task.ID,
+			WorkID:        task.WorkID,
+			Type:          string(task.Type),
+			Status:        string(task.Status),
+			Progress:      task.Progress,
+			Provider:      task.Provider,
+			Model:         task.Model,
+			CacheHit:      task.CacheHit,
+			ResultPreview: truncateRunes(task.Result, resultPreviewChars),
+			Error:         task.Error,
+			CreatedAt:     task.CreatedAt,
+			CompletedAt:   task.CompletedAt,
+		}
+		if task.InputTokens > 0 || task.OutputTokens > 0 {
+			items[i].Usage = &dto.AIUsage{
+				InputTokens:  task.InputTokens,
+				OutputTokens: task.OutputTokens,
+				Cost:         task.Cost,
+			}
+		}
+	}
+
+	return &dto.AITaskListResponse{
+		Tasks: items,
+		Pagination: dto.Pagination{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: int(math.Ceil(float64(total) / float64(params.Limit))),
+		},
+	}, nil
+}
+
+// DeleteTask 删除任务记录，未结束的任务先取消
+func (s *aiService) DeleteTask(userID, taskID uint) error {
+	task, err := s.findOwnedTask(userID, taskID)
+	if err != nil {
+		return err
+	}
+
+	if task.Status.IsActive() {
+		if _, err := s.CancelTask(userID, task.ID); err != nil && err != ErrAITaskNotCancellable {
+			return err
+		}
+	}
+	return s.aiTaskRepo.Delete(task.ID)
+}
+
+// isTaskType 是否为已知的任务类型
+func isTaskType(taskType string) bool {
+	for _, t := range model.AITaskTypes {
+		if string(t) == taskType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jugo/backend/internal/model"
+)
+
+// ErrInvalidModelOutput 模型没有按要求输出JSON
+var ErrInvalidModelOutput = errors.New("invalid model output")
+
+// maxJSONAttempts 结构化输出不合法时的最多生成次数（含首次）
+const maxJSONAttempts = 3
+
+// generateJSON 渲染模板并流式生成结构化输出，parse校验失败时把错误原因写入data.Feedback重新生成
+func (s *aiService) generateJSON(ctx context.Context, task *model.AITask, data *promptData, maxTokens int, parse func(output string) error) error {
+	client := s.clientFor(task.Type)
+	for attempt := 1; ; attempt++ {
+		prompt, err := s.renderPrompt(task, string(task.Type), data)
+		if err != nil {
+			return err
+		}
+		result, err := s.generateStream(ctx, task, client, prompt, maxTokens)
+		if err != nil {
+			return err
+		}
+		err = parse(result.Text)
+		if err == nil || attempt >= maxJSONAttempts || ctx.Err() != nil {
+			return err
+		}
+		data.Feedback = err.Error()
+	}
+}
+
+// decodeJSONOutput 解析模型输出中的JSON，忽略代码块标记和JSON前后的说明文字
+func decodeJSONOutput(output string, v interface{}) error {
+	start := strings.IndexAny(output, "{[")
+	if start < 0 {
+		return fmt.Errorf("%w: no JSON found", ErrInvalidModelOutput)
+	}
+	if err := json.NewDecoder(strings.NewReader(output[start:])).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidModelOutput, err)
+	}
+	return nil
+}
+
+// looseID 模型输出的ID，兼容数字和"12"、"章节12"等字符串写法
+type looseID uint
+
+// UnmarshalJSON 提取其中的数字部分，无法解析时为0
+func (id *looseID) UnmarshalJSON(data []byte) error {
+	var n uint
+	for _, r := range string(data) {
+		if r >= '0' && r <= '9' {
+			n = n*10 + uint(r-'0')
+		} else if n > 0 {
+			break
+		}
+	}
+	*id = looseID(n)
+	return nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/internal/moderation"
+)
+
+// ErrModerationRejected 生成结果包含需要拒绝的敏感词
+var ErrModerationRejected = errors.New("AI result rejected by content moderation")
+
+// moderate 按作品白名单审核生成结果并把发现记录到任务，返回处理后的文本；结果被拒绝时返回ErrModerationRejected
+func (s *aiService) moderate(task *model.AITask, text string) (string, error) {
+	if s.moderator == nil {
+		return text, nil
+	}
+
+	var allowed []string
+	if work, err := s.workRepo.FindByID(task.WorkID); err == nil {
+		allowed = work.Metadata.AllowedTerms
+	}
+	result := s.moderator.Check(text, allowed)
+	recordModeration(task, result)
+	if result.Action == moderation.ActionReject {
+		return "", ErrModerationRejected
+	}
+	return result.Text, nil
+}
+
+// recordModeration 合并审核发现：同一分类的同一个词只记录一次（取较大的次数），处理方式取最严重的
+//
+// 多候选的结果在生成后和完成任务时各审核一次，合并保证重复审核不会重复计数。
+func recordModeration(task *model.AITask, result *moderation.Result) {
+	if len(result.Findings) == 0 {
+		return
+	}
+	if task.Moderation == nil {
+		task.Moderation = &model.AITaskModeration{}
+	}
+	if result.Action.Severity() > moderation.Action(task.Moderation.Action).Severity() {
+		task.Moderation.Action = string(result.Action)
+	}
+
+	for _, f := range result.Findings {
+		merged := false
+		for i := range task.Moderation.Findings {
+			existing := &task.Moderation.Findings[i]
+			if existing.Category == f.Category && existing.Term == f.Term {
+				existing.Count = max(existing.Count, f.Count)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			task.Moderation.Findings = append(task.Moderation.Findings, model.ModerationFinding{
+				Category: f.Category,
+				Level:    f.Level,
+				Action:   string(f.Action),
+				Term:     f.Term,
+				Count:    f.Count,
+			})
+		}
+	}
+}
+
+// rejectTask 结果未通过审核时标记任务失败，清除部分结果并记录审核发现
+func (s *aiService) rejectTask(task *model.AITask, err error) {
+	if !s.writeTerminalStatus(task, func() (bool, error) { return s.aiTaskRepo.Reject(task, err.Error()) }) {
+		return
+	}
+
+	task.Status = model.AITaskStatusFailed
+	task.Error = err.Error()
+	task.Result = ""
+	s.quota.Release(context.Background(), task)
+	s.notify(task, &dto.AITaskEvent{Event: AITaskEventFailed, Error: task.Error})
+}
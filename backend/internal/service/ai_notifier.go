@@ -0,0 +1,23 @@
+package service
+
+import "github.com/jugo/backend/internal/dto"
+
+// AI任务事件类型
+const (
+	AITaskEventProgress  = "progress"
+	AITaskEventChunk     = "chunk"
+	AITaskEventCompleted = "completed"
+	AITaskEventFailed    = "failed"
+	AITaskEventCancelled = "cancelled"
+)
+
+// AITaskNotifier AI任务事件推送接口，由WebSocket等推送通道实现
+type AITaskNotifier interface {
+	NotifyAITask(userID uint, event *dto.AITaskEvent)
+}
+
+// noopNotifier 不推送任何事件的空实现
+type noopNotifier struct{}
+
+// NotifyAITask 实现AITaskNotifier接口
+func (noopNotifier) NotifyAITask(userID uint, event *dto.AITaskEvent) {}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+)
+
+var (
+	ErrOutlineNotMaterializable = errors.New("AI task is not a completed outline")
+	ErrOutlineMaterialized      = errors.New("outline chapters already created")
+	ErrInvalidOutlineChapters   = errors.New("invalid outline chapter selection")
+)
+
+const (
+	// maxOutlineTitleChars 章节标题最大字数，超出时视为模型没有按要求输出
+	maxOutlineTitleChars = 50
+	minOutlineMaxTokens  = 4096
+	maxOutlineMaxTokens  = 16384
+)
+
+// parseOutline 解析并校验模型输出的大纲：章节数须与要求一致，标题和梗概不能为空
+func parseOutline(output string, numChapters int) (*dto.OutlineResult, error) {
+	var outline dto.OutlineResult
+	if err := decodeJSONOutput(output, &outline); err != nil {
+		return nil, err
+	}
+	if len(outline.Chapters) != numChapters {
+		return nil, fmt.Errorf("%w: expected %d chapters, got %d", ErrInvalidModelOutput, numChapters, len(outline.Chapters))
+	}
+
+	outline.Synopsis = strings.TrimSpace(outline.Synopsis)
+	characters := outline.Characters[:0]
+	for _, c := range outline.Characters {
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			continue
+		}
+		c.Role = strings.TrimSpace(c.Role)
+		c.Description = strings.TrimSpace(c.Description)
+		characters = append(characters, c)
+	}
+	outline.Characters = characters
+
+	for i := range outline.Chapters {
+		ch := &outline.Chapters[i]
+		ch.Order = i + 1
+		ch.Title = strings.TrimSpace(chapterTitlePrefixPattern.ReplaceAllString(strings.TrimSpace(ch.Title), ""))
+		if ch.Title == "" || utf8.RuneCountInString(ch.Title) > maxOutlineTitleChars {
+			return nil, fmt.Errorf("%w: chapter %d has no valid title", ErrInvalidModelOutput, i+1)
+		}
+		ch.Synopsis = strings.TrimSpace(ch.Synopsis)
+		if ch.Synopsis == "" {
+			return nil, fmt.Errorf("%w: chapter %d has no synopsis", ErrInvalidModelOutput, i+1)
+		}
+		ch.Beats = compactStrings(ch.Beats)
+		ch.Characters = compactStrings(ch.Characters)
+	}
+	return &outline, nil
+}
+
+// compactStrings 去除首尾空白和空字符串
+func compactStrings(items []string) []string {
+	result := items[:0]
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// outlineMaxTokens 按章节数估算大纲输出的最大token数
+func outlineMaxTokens(numChapters int) int {
+	tokens := 1000 + numChapters*300
+	if tokens < minOutlineMaxTokens {
+		tokens = minOutlineMaxTokens
+	}
+	if tokens > maxOutlineMaxTokens {
+		tokens = maxOutlineMaxTokens
+	}
+	return tokens
+}
+
+// MaterializeOutline 按已完成的大纲任务在作品末尾批量创建章节草稿，章节内容为空，梗概来自大纲
+func (s *aiService) MaterializeOutline(userID, taskID uint, req *dto.MaterializeOutlineRequest) (*dto.MaterializeOutlineResponse, error) {
+	task, err := s.findOwnedTask(userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.Type != model.AITaskTypeOutline || task.Status != model.AITaskStatusCompleted {
+		return nil, ErrOutlineNotMaterializable
+	}
+	if task.MaterializedAt != nil {
+		return nil, ErrOutlineMaterialized
+	}
+
+	// 早期的大纲任务结果为纯文本，无法生成章节
+	var outline dto.OutlineResult
+	if err := json.Unmarshal([]byte(task.Result), &outline); err != nil || len(outline.Chapters) == 0 {
+		return nil, ErrOutlineNotMaterializable
+	}
+
+	selected := outline.Chapters
+	if len(req.Chapters) > 0 {
+		selected = make([]dto.OutlineChapter, 0, len(req.Chapters))
+		seen := make(map[int]bool, len(req.Chapters))
+		for _, n := range req.Chapters {
+			if n < 1 || n > len(outline.Chapters) || seen[n] {
+				return nil, ErrInvalidOutlineChapters
+			}
+			seen[n] = true
+			selected = append(selected, outline.Chapters[n-1])
+		}
+	}
+
+	// 早于启用审核完成的大纲没有经过审核，写入章节前再审核一次梗概
+	synopses := make([]string, len(selected))
+	for i, item := range selected {
+		if synopses[i], err = s.moderate(task, outlineSynopsis(item)); err != nil {
+			return nil, err
+		}
+	}
+
+	work, err := s.workRepo.FindByID(task.WorkID)
+	if err != nil {
+		return nil, ErrWorkNotFound
+	}
+	existing, err := s.chapterRepo.FindByWorkID(work.ID)
+	if err != nil {
+		return nil, err
+	}
+	nextOrder := 1
+	for _, ch := range existing {
+		if ch.OrderNum >= nextOrder {
+			nextOrder = ch.OrderNum + 1
+		}
+	}
+
+	// 先标记再创建，避免并发请求重复创建章节
+	marked, err := s.aiTaskRepo.MarkMaterialized(task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return nil, ErrOutlineMaterialized
+	}
+
+	chapters := make([]*model.Chapter, len(selected))
+	for i, item := range selected {
+		chapters[i] = &model.Chapter{
+			Title:    item.Title,
+			Synopsis: synopses[i],
+			OrderNum: nextOrder + i,
+			Status:   model.ChapterStatusDraft,
+		}
+	}
+	// 章节和作品统计在同一个事务中写入，失败时撤销标记以便重试
+	count, err := s.workRepo.AddChapters(work.ID, chapters)
+	if err != nil {
+		s.aiTaskRepo.ResetMaterialized(task.ID)
+		return nil, fmt.Errorf("failed to create outline chapters: %w", err)
+	}
+
+	resp := &dto.MaterializeOutlineResponse{
+		WorkID:   work.ID,
+		Chapters: make([]dto.AdaptationChapter, 0, len(chapters)),
+	}
+	for _, chapter := range chapters {
+		resp.Chapters = append(resp.Chapters, dto.AdaptationChapter{
+			ChapterID: chapter.ID,
+			Title:     chapter.Title,
+			Order:     chapter.OrderNum,
+		})
+	}
+	resp.NumChapters = count
+	return resp, nil
+}
+
+// outlineSynopsis 章节草稿的梗概：大纲梗概、关键情节点和出场角色
+func outlineSynopsis(ch dto.OutlineChapter) string {
+	var b strings.Builder
+	b.WriteString(ch.Synopsis)
+	if len(ch.Beats) > 0 {
+		b.WriteString("\n关键情节：")
+		for _, beat := range ch.Beats {
+			b.WriteString("\n- ")
+			b.WriteString(beat)
+		}
+	}
+	if len(ch.Characters) > 0 {
+		b.WriteString("\n出场角色：")
+		b.WriteString(strings.Join(ch.Characters, "、"))
+	}
+	return b.String()
+}
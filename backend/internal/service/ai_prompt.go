@@ -0,0 +1,75 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/internal/prompt"
+)
+
+// promptData 提示词模板数据
+type promptData struct {
+	Work       *model.Work
+	Req        interface{}
+	Characters []model.Character
+	Part       *convertPart           // 分段转换时的当前分段
+	Bible      string                 // 作品设定，未开启时为空
+	Chapter    *model.Chapter         // 续写所在章节，近期章节只取该章之前的章节
+	Feedback   string                 // 上次输出不符合要求的原因，重试时提示模型修正
+	Snowflake  *dto.SnowflakeResponse // 雪花写作法各步骤
+	Summaries  []model.Chapter        // 按顺序排列的章节摘要
+}
+
+// renderPrompt 按作品题材和类型选择模板渲染提示词，注入作品设定，并在任务上记录模板版本
+func (s *aiService) renderPrompt(task *model.AITask, name string, data *promptData) (string, error) {
+	rendered, err := s.renderTemplate(task, name, data)
+	if err != nil {
+		return "", err
+	}
+	return rendered.Text, nil
+}
+
+// renderTemplate 同renderPrompt，同时返回选中的模板版本和其声明的输出格式
+func (s *aiService) renderTemplate(task *model.AITask, name string, data *promptData) (*prompt.Rendered, error) {
+	if data.Work == nil {
+		work, err := s.workRepo.FindByID(task.WorkID)
+		if err != nil {
+			return nil, ErrWorkNotFound
+		}
+		data.Work = work
+	}
+	if data.Bible == "" && s.contextEnabled(data.Req) {
+		bible, err := s.buildStoryBible(task, data)
+		if err != nil {
+			return nil, err
+		}
+		data.Bible = bible
+	}
+
+	rendered, err := s.prompts.Render(name, prompt.Selector{
+		WorkType: string(data.Work.Type),
+		Genre:    data.Work.Genre,
+		UserID:   task.UserID,
+	}, data)
+	if err != nil {
+		return nil, err
+	}
+
+	addPromptVersion(task, rendered.Version)
+	return rendered, nil
+}
+
+// addPromptVersion 记录使用过的模板版本（去重）
+func addPromptVersion(task *model.AITask, version string) {
+	if task.PromptVersion == "" {
+		task.PromptVersion = version
+		return
+	}
+	for _, v := range strings.Split(task.PromptVersion, ",") {
+		if v == version {
+			return
+		}
+	}
+	task.PromptVersion += "," + version
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/jugo/backend/config"
+	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/pkg/ai"
+)
+
+// defaultRoute 路由配置中的默认链路键
+const defaultRoute = "default"
+
+// newTaskClients 按ai.routing配置，从注册表中为每种任务类型组装带重试和故障转移的客户端
+func newTaskClients(providers *ai.Registry, cfg *config.AIConfig) map[model.AITaskType]ai.Client {
+	policy := retryPolicy(cfg)
+
+	clients := make(map[model.AITaskType]ai.Client, len(model.AITaskTypes))
+	for _, taskType := range model.AITaskTypes {
+		var chain []ai.Client
+		for _, name := range routeFor(cfg, taskType) {
+			client, ok := providers.Get(name)
+			if !ok {
+				log.Printf("Unknown AI provider in routing: task=%s, provider=%s", taskType, name)
+				continue
+			}
+			chain = append(chain, client)
+		}
+		if len(chain) == 0 {
+			client, ok := fallbackClient(providers, taskType)
+			if !ok {
+				log.Printf("No AI provider available: task=%s", taskType)
+				continue
+			}
+			chain = []ai.Client{client}
+		}
+		clients[taskType] = ai.NewFailoverClient(chain, policy)
+	}
+	return clients
+}
+
+// retryPolicy 配置中的重试策略
+func retryPolicy(cfg *config.AIConfig) ai.RetryPolicy {
+	return ai.RetryPolicy{
+		MaxRetries:     cfg.Retry.MaxRetries,
+		InitialBackoff: time.Duration(cfg.Retry.InitialBackoffMs) * time.Millisecond,
+		MaxBackoff:     time.Duration(cfg.Retry.MaxBackoffMs) * time.Millisecond,
+	}
+}
+
+// fallbackClient 路由中的提供商均不可用时，按SelectProvider选择，仍不存在则使用任意已注册的提供商
+func fallbackClient(providers *ai.Registry, taskType model.AITaskType) (ai.Client, bool) {
+	if client, ok := providers.Get(string(ai.SelectProvider(string(taskType)))); ok {
+		return client, true
+	}
+	names := providers.Names()
+	if len(names) == 0 {
+		return nil, false
+	}
+	return providers.Get(names[0])
+}
+
+// routeFor 获取任务类型的提供商顺序：任务类型配置 > default配置 > 按SelectProvider选择主提供商并以另一个作为备用
+func routeFor(cfg *config.AIConfig, taskType model.AITaskType) []string {
+	if route, ok := cfg.Routing[string(taskType)]; ok && len(route) > 0 {
+		return route
+	}
+	if route, ok := cfg.Routing[defaultRoute]; ok && len(route) > 0 {
+		return route
+	}
+
+	primary := ai.SelectProvider(string(taskType))
+	if primary == ai.ProviderClaude {
+		return []string{string(ai.ProviderClaude), string(ai.ProviderDeepSeek)}
+	}
+	return []string{string(ai.ProviderDeepSeek), string(ai.ProviderClaude)}
+}
+
+// clientFor 获取任务类型对应的客户端
+func (s *aiService) clientFor(taskType model.AITaskType) ai.Client {
+	if client, ok := s.clients[taskType]; ok {
+		return client
+	}
+	return s.clients[model.AITaskTypeContinue]
+}
+
+// providerClient 按名称获取提供商客户端（按配置重试，不切换到其他提供商）
+func (s *aiService) providerClient(name string) (ai.Client, bool) {
+	client, ok := s.providers.Get(name)
+	if !ok {
+		return nil, false
+	}
+	return ai.NewFailoverClient([]ai.Client{client}, retryPolicy(&s.cfg.AI)), true
+}
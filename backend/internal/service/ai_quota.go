@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jugo/backend/config"
+	"github.com/jugo/backend/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// 配额类型
+const (
+	QuotaRequestsPerMinute = "requests_per_minute"
+	QuotaConcurrentTasks   = "concurrent_tasks"
+	QuotaMonthlyTokens     = "monthly_tokens"
+)
+
+const (
+	quotaKeyPrefix = "jugo:ai_quota:"
+	// defaultRunningTTL 未配置running_ttl时并发计数中任务的最长保留时间
+	defaultRunningTTL = time.Hour
+)
+
+// acquireSlotScript 清理过期任务后检查并发数，未超限时占用一个名额，超限时返回当前数量和最早任务的时间
+var acquireSlotScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[2])
+local card = redis.call('ZCARD', KEYS[1])
+if card >= tonumber(ARGV[3]) then
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	return {card, tonumber(oldest[2] or ARGV[1])}
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[4])
+return {-1, 0}
+`)
+
+// QuotaExceededError 超出AI配额
+type QuotaExceededError struct {
+	Limit   string    // 触发的配额类型
+	Max     int64     // 配额上限
+	Used    int64     // 当前已用量
+	ResetAt time.Time // 配额恢复时间
+}
+
+// Error 实现error接口
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("AI quota exceeded: %s (%d/%d)", e.Limit, e.Used, e.Max)
+}
+
+// aiQuota 基于Redis的用户AI配额，Redis不可用时放行请求
+type aiQuota struct {
+	rdb *redis.Client
+	cfg *config.AIQuotaConfig
+}
+
+// newAIQuota 创建配额控制，未启用时返回nil（nil上的方法均为空操作）
+func newAIQuota(rdb *redis.Client, cfg *config.AIQuotaConfig) *aiQuota {
+	if rdb == nil || !cfg.Enabled {
+		return nil
+	}
+	return &aiQuota{rdb: rdb, cfg: cfg}
+}
+
+// Check 检查用户是否可以创建新任务，通过时计入每分钟请求数并占用一个并发名额
+//
+// 返回的slot在任务创建后交给Track替换为任务ID，任务创建失败时交给Abandon释放。
+func (q *aiQuota) Check(ctx context.Context, userID uint) (string, error) {
+	if q == nil {
+		return "", nil
+	}
+	now := time.Now()
+
+	// 先检查只读的配额，避免被拒绝的请求也占用每分钟请求数
+	if q.cfg.MonthlyTokens > 0 {
+		used, err := q.rdb.Get(ctx, q.tokensKey(userID, now)).Int64()
+		if err != nil && err != redis.Nil {
+			log.Printf("Failed to check AI token quota: user=%d, err=%v", userID, err)
+		} else if used >= q.cfg.MonthlyTokens {
+			return "", &QuotaExceededError{
+				Limit:   QuotaMonthlyTokens,
+				Max:     q.cfg.MonthlyTokens,
+				Used:    used,
+				ResetAt: time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location()),
+			}
+		}
+	}
+
+	// 检查和占用在同一个脚本中完成，并发请求不会同时通过并发数检查
+	var slot string
+	if q.cfg.MaxConcurrent > 0 {
+		member := "slot:" + uuid.New().String()
+		cutoff := now.Add(-q.runningTTL()).Unix()
+		res, err := acquireSlotScript.Run(ctx, q.rdb, []string{q.runningKey(userID)},
+			now.Unix(), cutoff, q.cfg.MaxConcurrent, member).Int64Slice()
+		if err != nil {
+			log.Printf("Failed to check AI concurrency quota: user=%d, err=%v", userID, err)
+		} else if res[0] >= 0 {
+			// 无法预知任务何时结束，以最早任务的过期时间作为最晚恢复时间
+			return "", &QuotaExceededError{
+				Limit:   QuotaConcurrentTasks,
+				Max:     int64(q.cfg.MaxConcurrent),
+				Used:    res[0],
+				ResetAt: time.Unix(res[1], 0).Add(q.runningTTL()),
+			}
+		} else {
+			slot = member
+		}
+	}
+
+	if q.cfg.RequestsPerMinute > 0 {
+		window := now.Truncate(time.Minute)
+		key := quotaKeyPrefix + "rpm:" + strconv.FormatUint(uint64(userID), 10) + ":" + strconv.FormatInt(window.Unix(), 10)
+		pipe := q.rdb.TxPipeline()
+		incr := pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, time.Minute)
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("Failed to check AI rate limit: user=%d, err=%v", userID, err)
+		} else if incr.Val() > int64(q.cfg.RequestsPerMinute) {
+			q.Abandon(ctx, userID, slot)
+			return "", &QuotaExceededError{
+				Limit:   QuotaRequestsPerMinute,
+				Max:     int64(q.cfg.RequestsPerMinute),
+				Used:    incr.Val(),
+				ResetAt: window.Add(time.Minute),
+			}
+		}
+	}
+
+	return slot, nil
+}
+
+// Track 将Check占用的并发名额替换为新建的任务
+func (q *aiQuota) Track(ctx context.Context, task *model.AITask, slot string) {
+	if q == nil || q.cfg.MaxConcurrent <= 0 {
+		return
+	}
+	key := q.runningKey(task.UserID)
+	pipe := q.rdb.TxPipeline()
+	if slot != "" {
+		pipe.ZRem(ctx, key, slot)
+	}
+	pipe.ZAdd(ctx, key, redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: task.ID,
+	})
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to track AI task quota: id=%d, err=%v", task.ID, err)
+	}
+}
+
+// Abandon 释放Check占用但没有创建任务的并发名额
+func (q *aiQuota) Abandon(ctx context.Context, userID uint, slot string) {
+	if q == nil || slot == "" {
+		return
+	}
+	if err := q.rdb.ZRem(ctx, q.runningKey(userID), slot).Err(); err != nil {
+		log.Printf("Failed to release AI concurrency slot: user=%d, err=%v", userID, err)
+	}
+}
+
+// Release 任务结束后释放并发名额，并将用量计入本月token预算
+func (q *aiQuota) Release(ctx context.Context, task *model.AITask) {
+	if q == nil {
+		return
+	}
+
+	pipe := q.rdb.TxPipeline()
+	pipe.ZRem(ctx, q.runningKey(task.UserID), task.ID)
+	q.addTokens(ctx, pipe, task)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to release AI task quota: id=%d, err=%v", task.ID, err)
+	}
+}
+
+// AddUsage 将已释放名额的任务（如执行中被取消的任务）随后产生的用量计入本月token预算
+func (q *aiQuota) AddUsage(ctx context.Context, task *model.AITask) {
+	if q == nil {
+		return
+	}
+
+	pipe := q.rdb.TxPipeline()
+	q.addTokens(ctx, pipe, task)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to add AI task token usage: id=%d, err=%v", task.ID, err)
+	}
+}
+
+// addTokens 在pipeline中累加任务的token用量
+func (q *aiQuota) addTokens(ctx context.Context, pipe redis.Pipeliner, task *model.AITask) {
+	if tokens := int64(task.InputTokens + task.OutputTokens); tokens > 0 {
+		key := q.tokensKey(task.UserID, time.Now())
+		pipe.IncrBy(ctx, key, tokens)
+		// 保留到下个月初之后，足够覆盖当月
+		pipe.Expire(ctx, key, 32*24*time.Hour)
+	}
+}
+
+// runningTTL 并发计数中任务的最长保留时间
+func (q *aiQuota) runningTTL() time.Duration {
+	if q.cfg.RunningTTL <= 0 {
+		return defaultRunningTTL
+	}
+	return time.Duration(q.cfg.RunningTTL) * time.Second
+}
+
+// runningKey 用户未结束任务的有序集合
+func (q *aiQuota) runningKey(userID uint) string {
+	return quotaKeyPrefix + "running:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// tokensKey 用户当月token用量
+func (q *aiQuota) tokensKey(userID uint, now time.Time) string {
+	return quotaKeyPrefix + "tokens:" + strconv.FormatUint(uint64(userID), 10) + ":" + now.Format("200601")
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jugo/backend/config"
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/internal/queue"
+)
+
+const (
+	recoveryActionRequeue = "requeue"
+	recoveryActionFail    = "fail"
+
+	defaultSweepInterval = 60 * time.Second
+	defaultStaleAfter    = 5 * time.Minute
+	defaultMaxAttempts   = 3
+
+	// recoveryBatchSize 每种任务类型单次巡检处理的最大任务数
+	recoveryBatchSize = 100
+)
+
+// RunRecovery 启动时立即巡检一次，之后按配置间隔定期巡检中断的任务
+func (s *aiService) RunRecovery(ctx context.Context) {
+	interval := time.Duration(s.cfg.AI.Recovery.SweepInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultSweepInterval
+	}
+
+	s.recoverStaleTasks(ctx, true)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.recoverStaleTasks(ctx, false)
+		}
+	}
+}
+
+// recoverStaleTasks 按任务类型查找超时未更新的排队中和执行中任务，根据规则重新入队或标记失败
+//
+// 排队中的任务可能因入队失败或消费者崩溃而丢失了队列中的消息，超时后重新入队；
+// 内存队列在进程重启后会丢失全部任务，因此启动时把本进程启动前遗留的排队中和执行中任务全部视为中断。
+func (s *aiService) recoverStaleTasks(ctx context.Context, startup bool) {
+	cfg := &s.cfg.AI.Recovery
+	statuses := []model.AITaskStatus{model.AITaskStatusProcessing, model.AITaskStatusPending}
+	for _, taskType := range model.AITaskTypes {
+		rule := cfg.RuleFor(string(taskType))
+		staleAfter := time.Duration(rule.StaleAfter) * time.Second
+		if staleAfter <= 0 {
+			staleAfter = defaultStaleAfter
+		}
+
+		cutoff := time.Now().Add(-staleAfter)
+		if startup && s.cfg.Queue.Driver == queue.DriverMemory {
+			cutoff = s.startedAt
+		}
+
+		tasks, err := s.aiTaskRepo.FindStale(taskType, statuses, cutoff, recoveryBatchSize)
+		if err != nil {
+			log.Printf("Failed to find stale AI tasks: type=%s, err=%v", taskType, err)
+			continue
+		}
+
+		for _, task := range tasks {
+			if ctx.Err() != nil {
+				return
+			}
+			s.recoverTask(ctx, task, rule, statuses, cutoff)
+		}
+	}
+}
+
+// recoverTask 处理单个中断的任务
+func (s *aiService) recoverTask(ctx context.Context, task *model.AITask, rule config.AIRecoveryRule, statuses []model.AITaskStatus, cutoff time.Time) {
+	maxAttempts := s.cfg.AI.Recovery.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	// 排队中的任务尚未执行，无论规则如何都重新入队；队列中仍有消息时重复投递由认领逻辑去重
+	pending := task.Status == model.AITaskStatusPending
+	if pending || (rule.Action == recoveryActionRequeue && task.Attempts < maxAttempts) {
+		requeued, err := s.aiTaskRepo.Requeue(task.ID, statuses, cutoff)
+		if err != nil || !requeued {
+			return
+		}
+		if err := s.taskQueue.Enqueue(ctx, &queue.Job{TaskID: task.ID, Type: string(task.Type)}); err != nil {
+			s.failTask(task, fmt.Errorf("%w: %v", ErrEnqueueAITask, err))
+			return
+		}
+
+		task.Status = model.AITaskStatusPending
+		task.Progress = 0
+		s.notify(task, &dto.AITaskEvent{Event: AITaskEventProgress})
+		log.Printf("Requeued stale AI task: id=%d, type=%s, attempts=%d", task.ID, task.Type, task.Attempts)
+		return
+	}
+
+	// 未配置重新入队或已达最大执行次数时标记失败
+	reason := fmt.Sprintf("task interrupted: no progress since %s, worker may have stopped", task.UpdatedAt.Format(time.RFC3339))
+	if rule.Action == recoveryActionRequeue {
+		reason = fmt.Sprintf("task interrupted %d times, giving up", task.Attempts)
+	}
+	failed, err := s.aiTaskRepo.FailStale(task.ID, statuses, cutoff, reason)
+	if err != nil || !failed {
+		return
+	}
+
+	task.Status = model.AITaskStatusFailed
+	task.Error = reason
+	s.quota.Release(ctx, task)
+	s.notify(task, &dto.AITaskEvent{Event: AITaskEventFailed, Error: reason})
+	log.Printf("Marked stale AI task as failed: id=%d, type=%s", task.ID, task.Type)
+}
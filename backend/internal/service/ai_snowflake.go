@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+)
+
+const (
+	// defaultSnowflakeChapters 作品没有设置章节数时章节规划的预估章节数
+	defaultSnowflakeChapters     = 30
+	snowflakeTextMaxTokens       = 2048
+	snowflakeCharactersMaxTokens = 4096
+)
+
+// ExpandSnowflake 由雪花写作法的第Step步生成第Step+1步，结果需要用户确认后再保存
+func (s *aiService) ExpandSnowflake(userID uint, req *dto.SnowflakeExpandRequest) (*dto.AITaskResponse, error) {
+	// 验证作品权限
+	work, err := s.workRepo.FindByID(req.WorkID)
+	if err != nil {
+		return nil, ErrWorkNotFound
+	}
+	if work.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if completedSnowflakeSteps(work.Metadata.Snowflake) < req.Step {
+		return nil, ErrSnowflakeStepIncomplete
+	}
+
+	// 创建任务并加入队列
+	return s.createTask(userID, req.WorkID, model.AITaskTypeSnowflake, req, 30)
+}
+
+// processSnowflakeTask 处理雪花写作法步骤扩展任务，输出按保存步骤时的规则校验，不合法时重试
+func (s *aiService) processSnowflakeTask(ctx context.Context, task *model.AITask, req *dto.SnowflakeExpandRequest) {
+	s.updateProgress(task, 10)
+
+	work, err := s.workRepo.FindByID(req.WorkID)
+	if err != nil {
+		s.failTask(task, ErrWorkNotFound)
+		return
+	}
+	snowflake := snowflakeResponse(work)
+	if snowflake.CompletedSteps < req.Step {
+		s.failTask(task, ErrSnowflakeStepIncomplete)
+		return
+	}
+	characters, err := s.characterRepo.FindByWorkID(work.ID)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	target := req.Step + 1
+	data := &promptData{
+		Work:       work,
+		Req:        req,
+		Characters: characters,
+		Snowflake:  snowflake,
+	}
+
+	s.updateProgress(task, 30)
+	var step dto.SnowflakeStep
+	err = s.generateJSON(ctx, task, data, snowflakeMaxTokens(target, work), func(output string) error {
+		step = dto.SnowflakeStep{}
+		if err := decodeJSONOutput(output, &step); err != nil {
+			return err
+		}
+		if err := validateSnowflakeStep(target, &step); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidModelOutput, err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	step.Step = target
+	result, _ := json.Marshal(step)
+	s.completeTask(task, string(result))
+}
+
+// snowflakeMaxTokens 按目标步骤估算输出的最大token数
+func snowflakeMaxTokens(step int, work *model.Work) int {
+	switch step {
+	case 2:
+		return snowflakeTextMaxTokens
+	case 3:
+		return snowflakeCharactersMaxTokens
+	}
+	numChapters := work.NumChapters
+	if numChapters <= 0 {
+		numChapters = defaultSnowflakeChapters
+	}
+	return outlineMaxTokens(numChapters)
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/pkg/ai"
+)
+
+const (
+	defaultSummaryPollInterval = 30 * time.Second
+	defaultSummaryInputChars   = 20000
+	defaultSummaryMaxTokens    = 1024
+	defaultSynopsisTokens      = 8000
+	workSynopsisTemplate       = "work_synopsis"
+	summaryBatchSize           = 50
+	summaryEstimatedTime       = 30
+)
+
+// RunSummaryScheduler 按配置间隔查找防抖到期的章节并创建摘要任务
+func (s *aiService) RunSummaryScheduler(ctx context.Context) {
+	if !s.cfg.AI.Summary.Enabled {
+		return
+	}
+	interval := time.Duration(s.cfg.AI.Summary.PollInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultSummaryPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.scheduleDueSummaries(ctx)
+		}
+	}
+}
+
+// scheduleDueSummaries 认领到期的章节并创建摘要任务，多个进程同时巡检时每个章节只会被认领一次
+func (s *aiService) scheduleDueSummaries(ctx context.Context) {
+	chapters, err := s.chapterRepo.FindSummaryDue(time.Now(), summaryBatchSize)
+	if err != nil {
+		log.Printf("Failed to find chapters due for summary: %v", err)
+		return
+	}
+
+	for _, ch := range chapters {
+		if ctx.Err() != nil {
+			return
+		}
+		claimed, err := s.chapterRepo.ClaimSummaryDue(ch.ID, *ch.SummaryDueAt)
+		if err != nil || !claimed {
+			continue
+		}
+		work, err := s.workRepo.FindByID(ch.WorkID)
+		if err != nil {
+			continue
+		}
+
+		// 超出配额等原因无法创建任务时，变化字数仍保留，下次保存时会重新安排
+		req := &dto.ChapterSummaryRequest{WorkID: work.ID, ChapterID: ch.ID}
+		if _, err := s.createTask(work.UserID, work.ID, model.AITaskTypeChapterSummary, req, summaryEstimatedTime); err != nil {
+			log.Printf("Failed to create chapter summary task: chapter=%d, err=%v", ch.ID, err)
+		}
+	}
+}
+
+// processChapterSummaryTask 处理章节摘要任务：生成本章摘要后滚动更新全书梗概
+func (s *aiService) processChapterSummaryTask(ctx context.Context, task *model.AITask, req *dto.ChapterSummaryRequest) {
+	s.updateProgress(task, 10)
+
+	chapter, err := s.findWorkChapter(req.WorkID, req.ChapterID)
+	if err != nil {
+		s.failTask(task, err)
+		return
+	}
+	work, err := s.workRepo.FindByID(req.WorkID)
+	if err != nil {
+		s.failTask(task, ErrWorkNotFound)
+		return
+	}
+
+	// 生成期间的新变化不计入本次摘要
+	consumed := chapter.SummaryChanges
+	summary := ""
+	if text := htmlToText(chapter.Content); text != "" {
+		data := &promptData{
+			Work:    work,
+			Req:     req,
+			Chapter: chapter,
+			Part:    &convertPart{Text: truncateRunes(text, s.summaryInputChars())},
+		}
+		if prev, err := s.chapterRepo.FindPrevious(work.ID, chapter.OrderNum); err == nil && prev.Summary != "" {
+			data.Summaries = []model.Chapter{*prev}
+		}
+
+		s.updateProgress(task, 30)
+		summary, err = s.generateSummary(ctx, task, string(task.Type), data)
+		if err == nil {
+			summary, err = s.moderate(task, summary)
+		}
+		if err != nil {
+			s.failTask(task, err)
+			return
+		}
+	}
+	if err := s.chapterRepo.UpdateSummary(chapter.ID, summary, consumed); err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	s.updateProgress(task, 60)
+	if err := s.updateWorkSynopsis(ctx, task, work); err != nil {
+		s.failTask(task, err)
+		return
+	}
+
+	s.completeTask(task, summary)
+}
+
+// updateWorkSynopsis 以当前全书梗概和各章摘要重新生成全书梗概，摘要超出预算时只保留最近的章节
+func (s *aiService) updateWorkSynopsis(ctx context.Context, task *model.AITask, work *model.Work) error {
+	chapters, err := s.chapterRepo.FindSummaries(work.ID)
+	if err != nil {
+		return err
+	}
+
+	var summaries []model.Chapter
+	budget := s.synopsisTokens()
+	for i := len(chapters) - 1; i >= 0; i-- {
+		if chapters[i].Summary == "" {
+			continue
+		}
+		budget -= estimateTokens(chapters[i].Title) + estimateTokens(chapters[i].Summary)
+		if budget < 0 && len(summaries) > 0 {
+			break
+		}
+		summaries = append(summaries, chapters[i])
+	}
+	if len(summaries) == 0 {
+		return nil
+	}
+	for i, j := 0, len(summaries)-1; i < j; i, j = i+1, j-1 {
+		summaries[i], summaries[j] = summaries[j], summaries[i]
+	}
+
+	synopsis, err := s.generateSummary(ctx, task, workSynopsisTemplate, &promptData{
+		Work:      work,
+		Req:       &dto.ChapterSummaryRequest{WorkID: work.ID},
+		Summaries: summaries,
+	})
+	if err != nil {
+		return err
+	}
+	if synopsis, err = s.moderate(task, synopsis); err != nil {
+		return err
+	}
+	return s.workRepo.UpdateSynopsis(work.ID, synopsis)
+}
+
+// generateSummary 渲染模板并生成摘要（后台任务不需要流式推送）
+func (s *aiService) generateSummary(ctx context.Context, task *model.AITask, name string, data *promptData) (string, error) {
+	prompt, err := s.renderPrompt(task, name, data)
+	if err != nil {
+		return "", err
+	}
+	client := s.clientFor(task.Type)
+	result, err := s.generateCached(ctx, task, client, prompt, s.summaryMaxTokens(), 0, func() (*ai.Result, error) {
+		return client.Generate(ctx, prompt, s.summaryMaxTokens())
+	})
+	if err != nil {
+		return "", err
+	}
+	recordUsage(task, result)
+	return strings.TrimSpace(result.Text), nil
+}
+
+// summaryInputChars 生成摘要时读取的章节字数上限
+func (s *aiService) summaryInputChars() int {
+	if s.cfg.AI.Summary.InputChars > 0 {
+		return s.cfg.AI.Summary.InputChars
+	}
+	return defaultSummaryInputChars
+}
+
+// summaryMaxTokens 摘要和梗概输出的最大token数
+func (s *aiService) summaryMaxTokens() int {
+	if s.cfg.AI.Summary.MaxTokens > 0 {
+		return s.cfg.AI.Summary.MaxTokens
+	}
+	return defaultSummaryMaxTokens
+}
+
+// synopsisTokens 生成全书梗概时输入的章节摘要token上限
+func (s *aiService) synopsisTokens() int {
+	if s.cfg.AI.Summary.SynopsisTokens > 0 {
+		return s.cfg.AI.Summary.SynopsisTokens
+	}
+	return defaultSynopsisTokens
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidTextRange 文本范围超出内容长度
+var ErrInvalidTextRange = errors.New("invalid text range")
+
+var (
+	// blockTagPattern 块级结束标签和换行标签，转换为换行
+	blockTagPattern = regexp.MustCompile(`(?i)</p>|<br\s*/?>|</div>|</h[1-6]>|</li>`)
+	// tagPattern 其余HTML标签
+	tagPattern = regexp.MustCompile(`<[^>]*>`)
+	// blankLinesPattern 连续空行
+	blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+	// paragraphEndPattern 段落结束标签，编辑器中每个段落对应纯文本中的一行
+	paragraphEndPattern = regexp.MustCompile(`(?i)</(p|div|h[1-6]|li|blockquote|pre)>`)
+)
+
+// htmlToText 将编辑器保存的HTML章节内容转换为纯文本，段落之间以换行分隔
+func htmlToText(content string) string {
+	text := blockTagPattern.ReplaceAllString(content, "\n")
+	text = tagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+	text = blankLinesPattern.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// textToHTML 将生成的纯文本按段落转换为编辑器使用的HTML
+func textToHTML(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(html.EscapeString(line))
+		b.WriteString("</p>")
+	}
+	return b.String()
+}
+
+// countRunes 统计纯文本字数（不含空白）
+func countRunes(text string) int {
+	n := 0
+	for _, line := range strings.Fields(text) {
+		n += utf8.RuneCountInString(line)
+	}
+	return n
+}
+
+// estimateTokens 粗略估算文本的token数：中日韩文字约每字1个token，其他字符约每4个1个token
+func estimateTokens(text string) int {
+	wide, other := 0, 0
+	for _, r := range text {
+		if isWideRune(r) {
+			wide++
+		} else {
+			other++
+		}
+	}
+	return wide + (other+3)/4
+}
+
+// truncateTokens 截取文本开头不超过budget个token的部分，截断时以省略号结尾
+func truncateTokens(text string, budget int) string {
+	if budget <= 0 {
+		return ""
+	}
+	if estimateTokens(text) <= budget {
+		return text
+	}
+	wide, other := 0, 0
+	for i, r := range text {
+		if isWideRune(r) {
+			wide++
+		} else {
+			other++
+		}
+		if wide+(other+3)/4 > budget-1 {
+			return text[:i] + "…"
+		}
+	}
+	return text
+}
+
+// tailTokens 截取文本末尾不超过budget个token的部分，尽量从段落开头截起
+func tailTokens(text string, budget int) string {
+	if budget <= 0 {
+		return ""
+	}
+	if estimateTokens(text) <= budget {
+		return text
+	}
+	runes := []rune(text)
+	wide, other := 0, 0
+	start := len(runes)
+	for start > 0 {
+		if isWideRune(runes[start-1]) {
+			wide++
+		} else {
+			other++
+		}
+		if wide+(other+3)/4 > budget {
+			break
+		}
+		start--
+	}
+	// 截断处之后不远处有段落开头时从段落开头开始
+	tail := string(runes[start:])
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)/5 {
+		return strings.TrimLeft(tail[i:], "\n")
+	}
+	return tail
+}
+
+// isWideRune 是否为按每字1个token估算的字符
+func isWideRune(r rune) bool {
+	return r >= 0x2E80 && (unicode.Is(unicode.Han, r) || unicode.IsPunct(r) || unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Hangul))
+}
+
+// paragraph 章节HTML中的一个段落
+type paragraph struct {
+	html string
+	text string
+}
+
+// splitParagraphs 按段落结束标签切分章节HTML，tail为最后一个段落之后剩余的HTML；
+// 内容中没有段落标签时isHTML为false（空内容按HTML处理）
+func splitParagraphs(content string) (paras []paragraph, tail string, isHTML bool) {
+	ends := paragraphEndPattern.FindAllStringIndex(content, -1)
+	if len(ends) == 0 {
+		return nil, content, strings.TrimSpace(content) == ""
+	}
+
+	prev := 0
+	for _, loc := range ends {
+		segment := content[prev:loc[1]]
+		paras = append(paras, paragraph{html: segment, text: paragraphText(segment)})
+		prev = loc[1]
+	}
+	return paras, content[prev:], true
+}
+
+// paragraphText 单个段落的纯文本，与编辑器中的文本一致（标签之间的换行不计入）
+func paragraphText(segment string) string {
+	text := tagPattern.ReplaceAllString(segment, "")
+	text = strings.NewReplacer("\r", "", "\n", "").Replace(text)
+	text = html.UnescapeString(text)
+	return strings.ReplaceAll(text, "\u00a0", " ")
+}
+
+// linesToHTML 将纯文本逐行转换为段落，空行保留为空段落，保证转换前后纯文本一致
+func linesToHTML(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			b.WriteString("<p><br></p>")
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(html.EscapeString(line))
+		b.WriteString("</p>")
+	}
+	return b.String()
+}
+
+// plainText 章节内容对应的纯文本，段落之间以一个换行分隔
+func plainText(content string) string {
+	paras, tail, isHTML := splitParagraphs(content)
+	if !isHTML {
+		return tail
+	}
+	texts := make([]string, len(paras))
+	for i, p := range paras {
+		texts[i] = p.text
+	}
+	return strings.Join(texts, "\n")
+}
+
+// replaceText 将章节纯文本中[start, end)范围（按字计）替换为text，返回新的章节内容和被替换的原文。
+// HTML内容只重建范围涉及的段落，其余段落的HTML（含格式）保持不变
+func replaceText(content string, start, end int, text string) (string, string, error) {
+	paras, tail, isHTML := splitParagraphs(content)
+	if !isHTML {
+		runes := []rune(content)
+		if start < 0 || end < start || end > len(runes) {
+			return "", "", ErrInvalidTextRange
+		}
+		return string(runes[:start]) + text + string(runes[end:]), string(runes[start:end]), nil
+	}
+
+	// 定位范围起止所在的段落
+	first, last := -1, -1
+	offset, firstOffset := 0, 0
+	for i, p := range paras {
+		length := utf8.RuneCountInString(p.text)
+		if first < 0 && start <= offset+length {
+			first, firstOffset = i, offset
+		}
+		if first >= 0 && end <= offset+length {
+			last = i
+			break
+		}
+		offset += length + 1
+	}
+	if start < 0 || end < start {
+		return "", "", ErrInvalidTextRange
+	}
+	if len(paras) == 0 {
+		if end > 0 {
+			return "", "", ErrInvalidTextRange
+		}
+		return linesToHTML(text) + tail, "", nil
+	}
+	if last < 0 {
+		return "", "", ErrInvalidTextRange
+	}
+
+	texts := make([]string, 0, last-first+1)
+	for _, p := range paras[first : last+1] {
+		texts = append(texts, p.text)
+	}
+	runes := []rune(strings.Join(texts, "\n"))
+	s, e := start-firstOffset, end-firstOffset
+	removed := string(runes[s:e])
+	replaced := string(runes[:s]) + text + string(runes[e:])
+
+	var b strings.Builder
+	for _, p := range paras[:first] {
+		b.WriteString(p.html)
+	}
+	b.WriteString(linesToHTML(replaced))
+	for _, p := range paras[last+1:] {
+		b.WriteString(p.html)
+	}
+	b.WriteString(tail)
+	return b.String(), removed, nil
+}
+
+// changedRunes 估算两段文本之间变化的字数：去掉相同的开头和结尾后，取两者剩余部分中较长的一段
+func changedRunes(before, after string) int {
+	a, b := []rune(before), []rune(after)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return max(len(a), len(b)) - prefix - suffix
+}
+
+// contentHash 章节内容的SHA-256，用于检测内容是否被修改
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/pkg/ai"
+)
+
+// 用量统计周期
+const (
+	UsagePeriodDaily   = "daily"
+	UsagePeriodMonthly = "monthly"
+)
+
+const (
+	usageDateLayout = "2006-01-02"
+	// maxUsageRange 单次查询的最大时间跨度
+	maxUsageRange = 366 * 24 * time.Hour
+)
+
+// ErrInvalidUsageRange 用量查询时间范围无效
+var ErrInvalidUsageRange = errors.New("invalid usage date range")
+
+// recordUsage 累计一次生成的用量和成本，提供商和模型记录最后一次调用的值，命中缓存时标记任务
+func recordUsage(task *model.AITask, result *ai.Result) {
+	if result.Cached {
+		task.CacheHit = true
+	}
+	task.Provider = result.Provider
+	task.Model = result.Model
+	task.InputTokens += result.Usage.InputTokens
+	task.OutputTokens += result.Usage.OutputTokens
+	task.Cost += result.Cost
+}
+
+// GetUsage 按日或按月统计用户各任务类型的用量和成本
+func (s *aiService) GetUsage(userID uint, query *dto.AIUsageQuery) (*dto.AIUsageResponse, error) {
+	period := query.Period
+	if period == "" {
+		period = UsagePeriodDaily
+	}
+
+	// 默认统计范围：按日为最近30天，按月为最近12个月
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if query.To != "" {
+		t, err := time.ParseInLocation(usageDateLayout, query.To, time.Local)
+		if err != nil {
+			return nil, ErrInvalidUsageRange
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -29)
+	if period == UsagePeriodMonthly {
+		from = time.Date(to.Year(), to.Month()-11, 1, 0, 0, 0, 0, to.Location())
+	}
+	if query.From != "" {
+		f, err := time.ParseInLocation(usageDateLayout, query.From, time.Local)
+		if err != nil {
+			return nil, ErrInvalidUsageRange
+		}
+		from = f
+	}
+	if from.After(to) || to.Sub(from) > maxUsageRange {
+		return nil, ErrInvalidUsageRange
+	}
+
+	items, err := s.aiTaskRepo.SumUsage(userID, from, to.AddDate(0, 0, 1), period == UsagePeriodMonthly)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.AIUsageResponse{
+		Period: period,
+		From:   from.Format(usageDateLayout),
+		To:     to.Format(usageDateLayout),
+		Items:  items,
+	}
+	if resp.Items == nil {
+		resp.Items = []dto.AIUsageItem{}
+	}
+	for _, item := range items {
+		resp.Total.Tasks += item.Tasks
+		resp.Total.InputTokens += item.InputTokens
+		resp.Total.OutputTokens += item.OutputTokens
+		resp.Total.Cost += item.Cost
+	}
+	return resp, nil
+}
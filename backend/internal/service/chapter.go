@@ -55,6 +55,7 @@ func (s *chapterService) Create(userID, workID uint, req *dto.CreateChapterReque
 		WorkID:   workID,
 		Title:    req.Title,
 		Content:  req.Content,
+		Synopsis: req.Synopsis,
 		Words:    words,
 		OrderNum: req.OrderNum,
 		Status:   model.ChapterStatusDraft,
@@ -118,19 +119,23 @@ func (s *chapterService) List(userID, workID uint) (*dto.ChapterListResponse, er
 	items := make([]dto.ChapterListItem, len(chapters))
 	for i, chapter := range chapters {
 		items[i] = dto.ChapterListItem{
-			ChapterID: chapter.ID,
-			WorkID:    chapter.WorkID,
-			Title:     chapter.Title,
-			Words:     chapter.Words,
-			Order:     chapter.OrderNum,
-			Status:    string(chapter.Status),
-			CreatedAt: chapter.CreatedAt,
-			UpdatedAt: chapter.UpdatedAt,
+			ChapterID:    chapter.ID,
+			WorkID:       chapter.WorkID,
+			Title:        chapter.Title,
+			Synopsis:     chapter.Synopsis,
+			Summary:      chapter.Summary,
+			SummarizedAt: chapter.SummarizedAt,
+			Words:        chapter.Words,
+			Order:        chapter.OrderNum,
+			Status:       string(chapter.Status),
+			CreatedAt:    chapter.CreatedAt,
+			UpdatedAt:    chapter.UpdatedAt,
 		}
 	}
 
 	return &dto.ChapterListResponse{
-		Chapters: items,
+		Chapters:     items,
+		WorkSynopsis: work.Synopsis,
 	}, nil
 }
 
@@ -166,6 +171,9 @@ func (s *chapterService) Update(userID, workID, chapterID uint, req *dto.UpdateC
 		chapter.Words = s.countWords(req.Content)
 		needUpdateStats = true
 	}
+	if req.Synopsis != nil {
+		chapter.Synopsis = *req.Synopsis
+	}
 	if req.OrderNum > 0 {
 		chapter.OrderNum = req.OrderNum
 	}
@@ -258,14 +266,17 @@ func (s *chapterService) countWords(content string) int {
 // toChapterResponse 转换为章节响应
 func (s *chapterService) toChapterResponse(chapter *model.Chapter) *dto.ChapterResponse {
 	return &dto.ChapterResponse{
-		ChapterID: chapter.ID,
-		WorkID:    chapter.WorkID,
-		Title:     chapter.Title,
-		Content:   chapter.Content,
-		Words:     chapter.Words,
-		Order:     chapter.OrderNum,
-		Status:    string(chapter.Status),
-		CreatedAt: chapter.CreatedAt,
-		UpdatedAt: chapter.UpdatedAt,
+		ChapterID:    chapter.ID,
+		WorkID:       chapter.WorkID,
+		Title:        chapter.Title,
+		Content:      chapter.Content,
+		Synopsis:     chapter.Synopsis,
+		Summary:      chapter.Summary,
+		SummarizedAt: chapter.SummarizedAt,
+		Words:        chapter.Words,
+		Order:        chapter.OrderNum,
+		Status:       string(chapter.Status),
+		CreatedAt:    chapter.CreatedAt,
+		UpdatedAt:    chapter.UpdatedAt,
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jugo/backend/config"
 	"github.com/jugo/backend/internal/dto"
 	"github.com/jugo/backend/internal/repository"
 )
@@ -13,6 +14,11 @@ var (
 	ErrInvalidSaveType = errors.New("invalid save type")
 )
 
+const (
+	defaultSummaryMinChange = 500
+	defaultSummaryDebounce  = 5 * time.Minute
+)
+
 // SaveService 保存服务接口
 type SaveService interface {
 	AutoSave(userID, workID uint, req *dto.AutoSaveRequest) (*dto.SaveResponse, error)
@@ -23,13 +29,15 @@ type SaveService interface {
 type saveService struct {
 	workRepo    repository.WorkRepository
 	chapterRepo repository.ChapterRepository
+	cfg         *config.Config
 }
 
 // NewSaveService 创建保存服务
-func NewSaveService(workRepo repository.WorkRepository, chapterRepo repository.ChapterRepository) SaveService {
+func NewSaveService(workRepo repository.WorkRepository, chapterRepo repository.ChapterRepository, cfg *config.Config) SaveService {
 	return &saveService{
 		workRepo:    workRepo,
 		chapterRepo: chapterRepo,
+		cfg:         cfg,
 	}
 }
 
@@ -85,12 +93,16 @@ func (s *saveService) saveChapter(workID, chapterID uint, content string) (*dto.
 	}
 
 	// 更新内容和字数
+	changed := changedRunes(plainText(chapter.Content), plainText(content))
 	chapter.Content = content
 	chapter.Words = s.countWords(content)
 
 	if err := s.chapterRepo.Update(chapter); err != nil {
 		return nil, err
 	}
+	if err := s.markSummaryStale(chapter.ID, changed); err != nil {
+		return nil, err
+	}
 
 	// 更新作品统计
 	if err := s.updateWorkStatistics(workID); err != nil {
@@ -103,6 +115,27 @@ func (s *saveService) saveChapter(workID, chapterID uint, content string) (*dto.
 	}, nil
 }
 
+// markSummaryStale 累计自上次生成摘要以来变化的字数，超过阈值后每次保存都推迟摘要的生成时间，
+// 停止编辑一段时间后由后台任务重新生成
+func (s *saveService) markSummaryStale(chapterID uint, changed int) error {
+	cfg := &s.cfg.AI.Summary
+	if !cfg.Enabled || changed == 0 {
+		return nil
+	}
+
+	minChange := cfg.MinChangeChars
+	if minChange <= 0 {
+		minChange = defaultSummaryMinChange
+	}
+	debounce := time.Duration(cfg.Debounce) * time.Second
+	if debounce <= 0 {
+		debounce = defaultSummaryDebounce
+	}
+	// 数据库只保存到毫秒，截断后认领时才能按计划时间精确匹配
+	dueAt := time.Now().Add(debounce).Truncate(time.Millisecond)
+	return s.chapterRepo.AddSummaryChanges(chapterID, changed, minChange, dueAt)
+}
+
 // updateWorkStatistics 更新作品统计信息
 func (s *saveService) updateWorkStatistics(workID uint) error {
 	// 统计章节数
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jugo/backend/internal/dto"
+	"github.com/jugo/backend/internal/model"
+	"github.com/jugo/backend/internal/repository"
+)
+
+var (
+	ErrInvalidSnowflakeStep    = errors.New("invalid snowflake step")
+	ErrSnowflakeStepIncomplete = errors.New("previous snowflake step is not completed")
+)
+
+const (
+	// snowflakeSteps 雪花写作法步骤数：一句话概括 → 一段话梗概 → 角色设定 → 章节规划
+	snowflakeSteps           = 4
+	maxSnowflakeSentence     = 200
+	maxSnowflakeParagraph    = 3000
+	maxSnowflakeCharacters   = 30
+	maxSnowflakeChapters     = 1000
+	maxSnowflakeChapterTitle = 200
+	maxSnowflakeFieldChars   = 2000
+	// maxCharacterDescription 与创建角色请求的描述长度上限一致
+	maxCharacterDescription = 5000
+)
+
+// SnowflakeService 雪花写作法服务接口
+type SnowflakeService interface {
+	Get(userID, workID uint) (*dto.SnowflakeResponse, error)
+	UpdateStep(userID, workID uint, step int, req *dto.SnowflakeStep) (*dto.SnowflakeResponse, error)
+	CreateCharacters(userID, workID uint) (*dto.CharacterListResponse, error)
+}
+
+// snowflakeService 雪花写作法服务实现
+type snowflakeService struct {
+	workRepo         repository.WorkRepository
+	characterRepo    repository.CharacterRepository
+	characterService CharacterService
+}
+
+// NewSnowflakeService 创建雪花写作法服务
+func NewSnowflakeService(workRepo repository.WorkRepository, characterRepo repository.CharacterRepository, characterService CharacterService) SnowflakeService {
+	return &snowflakeService{
+		workRepo:         workRepo,
+		characterRepo:    characterRepo,
+		characterService: characterService,
+	}
+}
+
+// Get 获取作品的雪花写作法进度
+func (s *snowflakeService) Get(userID, workID uint) (*dto.SnowflakeResponse, error) {
+	work, err := s.findOwnedWork(userID, workID)
+	if err != nil {
+		return nil, err
+	}
+	return snowflakeResponse(work), nil
+}
+
+// UpdateStep 校验并保存一个步骤，前一步未完成时不能保存后面的步骤
+func (s *snowflakeService) UpdateStep(userID, workID uint, step int, req *dto.SnowflakeStep) (*dto.SnowflakeResponse, error) {
+	work, err := s.findOwnedWork(userID, workID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSnowflakeStep(step, req); err != nil {
+		return nil, err
+	}
+
+	data := work.Metadata.Snowflake
+	if data == nil {
+		data = &model.SnowflakeData{}
+	}
+	if completedSnowflakeSteps(data) < step-1 {
+		return nil, ErrSnowflakeStepIncomplete
+	}
+
+	applySnowflakeStep(data, step, req)
+	work.Metadata.Snowflake = data
+	if err := s.workRepo.UpdateMetadata(work.ID, work.Metadata); err != nil {
+		return nil, err
+	}
+	return snowflakeResponse(work), nil
+}
+
+// CreateCharacters 将第三步的角色设定创建为角色，已存在同名角色时跳过
+func (s *snowflakeService) CreateCharacters(userID, workID uint) (*dto.CharacterListResponse, error) {
+	work, err := s.findOwnedWork(userID, workID)
+	if err != nil {
+		return nil, err
+	}
+	profiles := snowflakeCharacters(work.Metadata.Snowflake)
+	if len(profiles) == 0 {
+		return nil, ErrSnowflakeStepIncomplete
+	}
+
+	existing, err := s.characterRepo.FindByWorkID(work.ID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(existing))
+	for _, c := range existing {
+		names[c.Name] = true
+	}
+
+	resp := &dto.CharacterListResponse{Characters: []dto.CharacterListItem{}}
+	for _, profile := range profiles {
+		if names[profile.Name] {
+			continue
+		}
+		names[profile.Name] = true
+
+		created, err := s.characterService.Create(userID, work.ID, &dto.CreateCharacterRequest{
+			Name:        profile.Name,
+			Role:        profile.Role,
+			Description: characterDescription(profile),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create character %s: %w", profile.Name, err)
+		}
+		resp.Characters = append(resp.Characters, dto.CharacterListItem{
+			CharacterID: created.CharacterID,
+			WorkID:      created.WorkID,
+			Name:        created.Name,
+			Role:        created.Role,
+			Description: created.Description,
+			CreatedAt:   created.CreatedAt,
+			UpdatedAt:   created.UpdatedAt,
+		})
+	}
+	return resp, nil
+}
+
+// findOwnedWork 查找当前用户的作品
+func (s *snowflakeService) findOwnedWork(userID, workID uint) (*model.Work, error) {
+	work, err := s.workRepo.FindByID(workID)
+	if err != nil {
+		return nil, ErrWorkNotFound
+	}
+	if work.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return work, nil
+}
+
+// validateSnowflakeStep 校验步骤内容并去除首尾空白
+func validateSnowflakeStep(step int, req *dto.SnowflakeStep) error {
+	switch step {
+	case 1:
+		req.Text = strings.TrimSpace(req.Text)
+		if req.Text == "" || strings.Contains(req.Text, "\n") {
+			return fmt.Errorf("%w: step 1 must be a single sentence", ErrInvalidSnowflakeStep)
+		}
+		if utf8.RuneCountInString(req.Text) > maxSnowflakeSentence {
+			return fmt.Errorf("%w: step 1 exceeds %d characters", ErrInvalidSnowflakeStep, maxSnowflakeSentence)
+		}
+	case 2:
+		req.Text = strings.TrimSpace(req.Text)
+		if req.Text == "" {
+			return fmt.Errorf("%w: step 2 requires a paragraph", ErrInvalidSnowflakeStep)
+		}
+		if utf8.RuneCountInString(req.Text) > maxSnowflakeParagraph {
+			return fmt.Errorf("%w: step 2 exceeds %d characters", ErrInvalidSnowflakeStep, maxSnowflakeParagraph)
+		}
+	case 3:
+		if len(req.Characters) == 0 || len(req.Characters) > maxSnowflakeCharacters {
+			return fmt.Errorf("%w: step 3 requires 1-%d characters", ErrInvalidSnowflakeStep, maxSnowflakeCharacters)
+		}
+		names := make(map[string]bool, len(req.Characters))
+		for i := range req.Characters {
+			c := &req.Characters[i]
+			c.Name = strings.TrimSpace(c.Name)
+			if c.Name == "" || utf8.RuneCountInString(c.Name) > 100 {
+				return fmt.Errorf("%w: character %d has no valid name", ErrInvalidSnowflakeStep, i+1)
+			}
+			if names[c.Name] {
+				return fmt.Errorf("%w: duplicate character %s", ErrInvalidSnowflakeStep, c.Name)
+			}
+			names[c.Name] = true
+			c.Role = strings.TrimSpace(c.Role)
+			if _, ok := roleLabels[model.CharacterRole(c.Role)]; c.Role != "" && !ok {
+				return fmt.Errorf("%w: character %s has invalid role %s", ErrInvalidSnowflakeStep, c.Name, c.Role)
+			}
+			for _, field := range []*string{&c.Description, &c.Motivation, &c.Goal, &c.Conflict, &c.Epiphany} {
+				*field = strings.TrimSpace(*field)
+				if utf8.RuneCountInString(*field) > maxSnowflakeFieldChars {
+					return fmt.Errorf("%w: character %s exceeds %d characters per field", ErrInvalidSnowflakeStep, c.Name, maxSnowflakeFieldChars)
+				}
+			}
+		}
+	case 4:
+		if len(req.Chapters) == 0 || len(req.Chapters) > maxSnowflakeChapters {
+			return fmt.Errorf("%w: step 4 requires 1-%d chapters", ErrInvalidSnowflakeStep, maxSnowflakeChapters)
+		}
+		for i := range req.Chapters {
+			ch := &req.Chapters[i]
+			ch.Title = strings.TrimSpace(ch.Title)
+			ch.Synopsis = strings.TrimSpace(ch.Synopsis)
+			if ch.Title == "" || utf8.RuneCountInString(ch.Title) > maxSnowflakeChapterTitle {
+				return fmt.Errorf("%w: chapter %d has no valid title", ErrInvalidSnowflakeStep, i+1)
+			}
+			if ch.Synopsis == "" {
+				return fmt.Errorf("%w: chapter %d has no synopsis", ErrInvalidSnowflakeStep, i+1)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: step must be between 1 and %d", ErrInvalidSnowflakeStep, snowflakeSteps)
+	}
+	return nil
+}
+
+// applySnowflakeStep 写入已校验的步骤内容
+func applySnowflakeStep(data *model.SnowflakeData, step int, req *dto.SnowflakeStep) {
+	switch step {
+	case 1:
+		data.Step1 = req.Text
+	case 2:
+		data.Step2 = req.Text
+	case 3:
+		profiles := make([]map[string]interface{}, 0, len(req.Characters))
+		for _, c := range req.Characters {
+			var profile map[string]interface{}
+			raw, _ := json.Marshal(c)
+			json.Unmarshal(raw, &profile)
+			profiles = append(profiles, profile)
+		}
+		data.Step3 = profiles
+	case 4:
+		chapters := make([]model.SnowflakeChapter, len(req.Chapters))
+		for i, ch := range req.Chapters {
+			chapters[i] = model.SnowflakeChapter{Title: ch.Title, Synopsis: ch.Synopsis}
+		}
+		data.Chapters = chapters
+		data.Step4 = true
+	}
+}
+
+// completedSnowflakeSteps 从第一步起连续完成的步骤数
+func completedSnowflakeSteps(data *model.SnowflakeData) int {
+	if data == nil || strings.TrimSpace(data.Step1) == "" {
+		return 0
+	}
+	if strings.TrimSpace(data.Step2) == "" {
+		return 1
+	}
+	if len(snowflakeCharacters(data)) == 0 {
+		return 2
+	}
+	if len(data.Chapters) == 0 {
+		return 3
+	}
+	return 4
+}
+
+// snowflakeCharacters 第三步的角色设定，忽略没有名字的条目（早期数据为前端直接写入的任意结构）
+func snowflakeCharacters(data *model.SnowflakeData) []dto.SnowflakeCharacter {
+	if data == nil {
+		return nil
+	}
+	characters := make([]dto.SnowflakeCharacter, 0, len(data.Step3))
+	for _, profile := range data.Step3 {
+		var c dto.SnowflakeCharacter
+		raw, _ := json.Marshal(profile)
+		if err := json.Unmarshal(raw, &c); err != nil {
+			// 字段类型不符时只保留名字、类型和简介
+			c = dto.SnowflakeCharacter{
+				Name:        profileString(profile, "name"),
+				Role:        profileString(profile, "role"),
+				Description: profileString(profile, "description"),
+			}
+		}
+		if c.Name = strings.TrimSpace(c.Name); c.Name != "" {
+			characters = append(characters, c)
+		}
+	}
+	return characters
+}
+
+// snowflakeResponse 作品的雪花写作法进度
+func snowflakeResponse(work *model.Work) *dto.SnowflakeResponse {
+	resp := &dto.SnowflakeResponse{
+		WorkID: work.ID,
+		Step3:  []dto.SnowflakeCharacter{},
+		Step4:  []dto.SnowflakeChapter{},
+	}
+	data := work.Metadata.Snowflake
+	if data == nil {
+		return resp
+	}
+	resp.Step1 = data.Step1
+	resp.Step2 = data.Step2
+	resp.Step3 = snowflakeCharacters(data)
+	for _, ch := range data.Chapters {
+		resp.Step4 = append(resp.Step4, dto.SnowflakeChapter{Title: ch.Title, Synopsis: ch.Synopsis})
+	}
+	resp.CompletedSteps = completedSnowflakeSteps(data)
+	return resp
+}
+
+// characterDescription 将角色设定的各项合并为角色描述
+func characterDescription(c dto.SnowflakeCharacter) string {
+	lines := splitLines(c.Description)
+	for _, item := range []struct{ label, value string }{
+		{"动机", c.Motivation},
+		{"目标", c.Goal},
+		{"冲突", c.Conflict},
+		{"顿悟", c.Epiphany},
+	} {
+		if item.value != "" {
+			lines = append(lines, item.label+"："+item.value)
+		}
+	}
+	return truncateRunes(strings.Join(lines, "\n"), maxCharacterDescription-1)
+}
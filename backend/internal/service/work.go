@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"math"
+	"strings"
 
 	"github.com/jugo/backend/internal/dto"
 	"github.com/jugo/backend/internal/model"
@@ -24,6 +25,7 @@ type WorkService interface {
 	List(userID uint, params *dto.WorkQueryParams) (*dto.WorkListResponse, error)
 	Update(userID, workID uint, req *dto.UpdateWorkRequest) (*dto.WorkResponse, error)
 	Delete(userID, workID uint) error
+	UpdateAllowedTerms(userID, workID uint, req *dto.UpdateAllowedTermsRequest) (*dto.WorkResponse, error)
 }
 
 // workService 作品服务实现
@@ -93,16 +95,17 @@ func (s *workService) List(userID uint, params *dto.WorkQueryParams) (*dto.WorkL
 	items := make([]dto.WorkListItem, len(works))
 	for i, work := range works {
 		items[i] = dto.WorkListItem{
-			WorkID:      work.ID,
-			Type:        string(work.Type),
-			Title:       work.Title,
-			Genre:       work.Genre,
-			Status:      string(work.Status),
-			Words:       work.Words,
-			NumChapters: work.NumChapters,
-			CoverImage:  work.CoverImage,
-			CreatedAt:   work.CreatedAt,
-			UpdatedAt:   work.UpdatedAt,
+			WorkID:       work.ID,
+			Type:         string(work.Type),
+			Title:        work.Title,
+			Genre:        work.Genre,
+			Status:       string(work.Status),
+			Words:        work.Words,
+			NumChapters:  work.NumChapters,
+			CoverImage:   work.CoverImage,
+			SourceWorkID: work.SourceWorkID,
+			CreatedAt:    work.CreatedAt,
+			UpdatedAt:    work.UpdatedAt,
 		}
 	}
 
@@ -188,6 +191,35 @@ func (s *workService) Delete(userID, workID uint) error {
 	return s.workRepo.Delete(workID)
 }
 
+// UpdateAllowedTerms 更新作品的审核白名单，去除首尾空白和重复的词
+func (s *workService) UpdateAllowedTerms(userID, workID uint, req *dto.UpdateAllowedTermsRequest) (*dto.WorkResponse, error) {
+	work, err := s.workRepo.FindByID(workID)
+	if err != nil {
+		return nil, ErrWorkNotFound
+	}
+
+	// 验证权限
+	if work.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	terms := make([]string, 0, len(req.Terms))
+	seen := make(map[string]bool, len(req.Terms))
+	for _, t := range req.Terms {
+		if t = strings.TrimSpace(t); t != "" && !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	work.Metadata.AllowedTerms = terms
+
+	if err := s.workRepo.UpdateMetadata(work.ID, work.Metadata); err != nil {
+		return nil, err
+	}
+
+	return s.toWorkResponse(work), nil
+}
+
 // toWorkResponse 转换为作品响应
 func (s *workService) toWorkResponse(work *model.Work) *dto.WorkResponse {
 	return &dto.WorkResponse{
@@ -201,7 +233,9 @@ func (s *workService) toWorkResponse(work *model.Work) *dto.WorkResponse {
 		NumChapters:    work.NumChapters,
 		WordPerChapter: work.WordPerChapter,
 		CoverImage:     work.CoverImage,
+		Synopsis:       work.Synopsis,
 		Metadata:       &work.Metadata,
+		SourceWorkID:   work.SourceWorkID,
 		CreatedAt:      work.CreatedAt,
 		UpdatedAt:      work.UpdatedAt,
 	}
@@ -0,0 +1,6 @@
+-- 005_add_ai_task_recovery_fields.sql
+
+-- 记录任务被worker认领的次数，用于恢复时限制重试
+ALTER TABLE ai_tasks
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0 AFTER progress,
+    ADD INDEX idx_status_updated_at (status, updated_at);
@@ -0,0 +1,5 @@
+-- 006_add_ai_task_provider.sql
+
+-- 记录实际生成结果的AI提供商（故障转移后可能与默认提供商不同）
+ALTER TABLE ai_tasks
+    ADD COLUMN provider VARCHAR(50) NULL AFTER status;
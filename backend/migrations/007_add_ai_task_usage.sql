@@ -0,0 +1,9 @@
+-- 007_add_ai_task_usage.sql
+
+-- 记录AI任务的模型、token用量和成本，用于计费与预算
+ALTER TABLE ai_tasks
+    ADD COLUMN model VARCHAR(100) NULL AFTER provider,
+    ADD COLUMN input_tokens INT NOT NULL DEFAULT 0 AFTER model,
+    ADD COLUMN output_tokens INT NOT NULL DEFAULT 0 AFTER input_tokens,
+    ADD COLUMN cost DECIMAL(12,6) NOT NULL DEFAULT 0 AFTER output_tokens,
+    ADD INDEX idx_user_created_at (user_id, created_at);
@@ -0,0 +1,5 @@
+-- 008_add_ai_task_prompt_version.sql
+
+-- 记录生成时使用的提示词模板版本，用于对比不同版本的效果
+ALTER TABLE ai_tasks
+    ADD COLUMN prompt_version VARCHAR(255) NULL AFTER cost;
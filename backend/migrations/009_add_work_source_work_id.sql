@@ -0,0 +1,6 @@
+-- 009_add_work_source_work_id.sql
+
+-- 记录改编作品的来源作品（如剧本转小说生成的新作品）
+ALTER TABLE works
+    ADD COLUMN source_work_id BIGINT UNSIGNED NULL AFTER metadata,
+    ADD INDEX idx_source_work_id (source_work_id);
@@ -0,0 +1,30 @@
+-- 010_create_ai_applications_table.sql
+
+-- 记录AI任务结果应用到章节的操作，用于撤销
+CREATE TABLE IF NOT EXISTS ai_applications (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+
+    task_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    work_id BIGINT UNSIGNED NOT NULL,
+    chapter_id BIGINT UNSIGNED NOT NULL,
+
+    range_start INT NOT NULL COMMENT '被替换范围起始（纯文本字偏移）',
+    range_end INT NOT NULL COMMENT '被替换范围结束（纯文本字偏移）',
+    removed TEXT COMMENT '被替换的原文',
+    inserted TEXT COMMENT '写入的新文本',
+
+    content_before LONGTEXT COMMENT '应用前的章节内容',
+    content_hash VARCHAR(64) COMMENT '应用后章节内容的SHA-256',
+
+    undone_at DATETIME(3) NULL,
+
+    INDEX idx_task_id (task_id),
+    INDEX idx_user_id (user_id),
+    INDEX idx_chapter_id (chapter_id),
+
+    FOREIGN KEY (task_id) REFERENCES ai_tasks(id) ON DELETE CASCADE,
+    FOREIGN KEY (chapter_id) REFERENCES chapters(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,26 @@
+-- 011_create_ai_task_candidates_table.sql
+
+-- 一次请求生成的多个候选结果
+CREATE TABLE IF NOT EXISTS ai_task_candidates (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    updated_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+
+    task_id BIGINT UNSIGNED NOT NULL,
+    candidate_index INT NOT NULL COMMENT '候选序号，从0开始',
+
+    provider VARCHAR(50),
+    model VARCHAR(100),
+    text TEXT,
+    error TEXT,
+
+    input_tokens INT NOT NULL DEFAULT 0,
+    output_tokens INT NOT NULL DEFAULT 0,
+    cost DECIMAL(12,6) NOT NULL DEFAULT 0,
+
+    chosen TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否为选中的候选',
+
+    UNIQUE INDEX idx_task_candidate (task_id, candidate_index),
+
+    FOREIGN KEY (task_id) REFERENCES ai_tasks(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,9 @@
+-- 012_add_chapter_synopsis_and_outline_materialized.sql
+
+-- 章节梗概（由大纲生成的章节在动笔前只有梗概）
+ALTER TABLE chapters
+    ADD COLUMN synopsis TEXT NULL AFTER content;
+
+-- 大纲任务已生成章节的时间，避免重复创建章节
+ALTER TABLE ai_tasks
+    ADD COLUMN materialized_at DATETIME(3) NULL AFTER completed_at;
@@ -0,0 +1,13 @@
+-- 013_add_chapter_summaries.sql
+
+-- 章节摘要：内容累计变化较大时由后台任务重新生成
+ALTER TABLE chapters
+    ADD COLUMN summary TEXT NULL AFTER synopsis,
+    ADD COLUMN summary_changes INT NOT NULL DEFAULT 0 AFTER summary,
+    ADD COLUMN summary_due_at DATETIME(3) NULL AFTER summary_changes,
+    ADD COLUMN summarized_at DATETIME(3) NULL AFTER summary_due_at,
+    ADD INDEX idx_chapters_summary_due_at (summary_due_at);
+
+-- 全书梗概：由各章摘要滚动更新
+ALTER TABLE works
+    ADD COLUMN synopsis TEXT NULL AFTER topic;
@@ -0,0 +1,5 @@
+-- 014_add_ai_task_cache_hit.sql
+
+-- 任务结果来自生成结果缓存（未实际调用提供商）
+ALTER TABLE ai_tasks
+    ADD COLUMN cache_hit TINYINT(1) NOT NULL DEFAULT 0 AFTER cost;
@@ -0,0 +1,5 @@
+-- 015_add_ai_task_moderation.sql
+
+-- 生成结果的审核发现（处理方式与命中的敏感词）
+ALTER TABLE ai_tasks
+    ADD COLUMN moderation JSON NULL AFTER error;
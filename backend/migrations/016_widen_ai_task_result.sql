@@ -0,0 +1,6 @@
+-- 016_widen_ai_task_result.sql
+
+-- 整部作品的转换结果和较长的请求参数会超出TEXT的64KB上限
+ALTER TABLE ai_tasks
+    MODIFY COLUMN parameters MEDIUMTEXT NULL,
+    MODIFY COLUMN result MEDIUMTEXT NULL;
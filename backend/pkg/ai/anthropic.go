@@ -0,0 +1,146 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jugo/backend/config"
+)
+
+const (
+	anthropicBaseURL = "https://api.anthropic.com"
+	anthropicVersion = "2023-06-01"
+)
+
+// anthropicClient Anthropic Messages API客户端
+type anthropicClient struct {
+	httpClient
+}
+
+// newAnthropicClient 创建Anthropic客户端
+func newAnthropicClient(name string, cfg *config.AIProviderConfig) (Client, error) {
+	return &anthropicClient{httpClient: newHTTPClient(name, cfg, anthropicBaseURL)}, nil
+}
+
+// setAuth 设置认证头
+func (c *anthropicClient) setAuth(req *http.Request) {
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+}
+
+// Generate 生成文本（非流式）
+func (c *anthropicClient) Generate(ctx context.Context, prompt string, maxTokens int) (*Result, error) {
+	reqBody := map[string]interface{}{
+		"model":      c.model,
+		"messages":   userMessages(prompt),
+		"max_tokens": c.tokens(maxTokens),
+	}
+
+	var resp struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+		Usage      struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := c.postJSON(ctx, "/v1/messages", reqBody, c.setAuth, &resp); err != nil {
+		return nil, err
+	}
+
+	result := c.newResult()
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			result.Text += block.Text
+		}
+	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("failed to extract text from response")
+	}
+	result.StopReason = resp.StopReason
+	result.Usage = Usage{InputTokens: resp.Usage.InputTokens, OutputTokens: resp.Usage.OutputTokens}
+	return c.finish(result), nil
+}
+
+// anthropicStreamEvent Anthropic流式事件结构
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// GenerateStream 生成文本（流式）
+func (c *anthropicClient) GenerateStream(ctx context.Context, prompt string, maxTokens int, callback func(string)) (*Result, error) {
+	reqBody := map[string]interface{}{
+		"model":      c.model,
+		"messages":   userMessages(prompt),
+		"max_tokens": c.tokens(maxTokens),
+		"stream":     true,
+	}
+
+	resp, err := c.post(ctx, "/v1/messages", reqBody, true, c.setAuth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := c.newResult()
+	var text strings.Builder
+	err = readSSE(resp.Body, func(ev *sseEvent) (bool, error) {
+		var payload anthropicStreamEvent
+		if err := json.Unmarshal([]byte(ev.Data), &payload); err != nil {
+			return false, fmt.Errorf("failed to unmarshal stream event: %w", err)
+		}
+
+		switch payload.Type {
+		case "message_start":
+			result.Usage.InputTokens = payload.Message.Usage.InputTokens
+			result.Usage.OutputTokens = payload.Message.Usage.OutputTokens
+		case "content_block_delta":
+			if payload.Delta.Type == "text_delta" && payload.Delta.Text != "" {
+				text.WriteString(payload.Delta.Text)
+				callback(payload.Delta.Text)
+			}
+		case "message_delta":
+			if payload.Delta.StopReason != "" {
+				result.StopReason = payload.Delta.StopReason
+			}
+			if payload.Usage.OutputTokens > 0 {
+				result.Usage.OutputTokens = payload.Usage.OutputTokens
+			}
+		case "message_stop":
+			return false, nil
+		case "error":
+			return false, fmt.Errorf("API stream error (%s): %s", payload.Error.Type, payload.Error.Message)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Text = text.String()
+	return c.finish(result), nil
+}
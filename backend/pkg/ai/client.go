@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/jugo/backend/config"
@@ -22,167 +23,213 @@ const (
 
 // Client AI客户端接口
 type Client interface {
-	Generate(ctx context.Context, prompt string, maxTokens int) (string, error)
-	GenerateStream(ctx context.Context, prompt string, maxTokens int, callback func(string)) error
+	// Name 提供商名称
+	Name() string
+	// Generate 非流式生成
+	Generate(ctx context.Context, prompt string, maxTokens int) (*Result, error)
+	// GenerateStream 流式生成，每收到一段增量文本调用一次callback，结束后返回完整结果
+	GenerateStream(ctx context.Context, prompt string, maxTokens int, callback func(string)) (*Result, error)
 }
 
-// client AI客户端实现
-type client struct {
-	provider   Provider
-	apiKey     string
-	model      string
-	maxTokens  int
-	timeout    int
-	httpClient *http.Client
+// Usage token用量
+type Usage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
 }
 
-// NewClient 创建AI客户端
-func NewClient(provider Provider, cfg *config.AIProviderConfig) Client {
-	return &client{
-		provider:  provider,
-		apiKey:    cfg.APIKey,
-		model:     cfg.Model,
-		maxTokens: cfg.MaxTokens,
-		timeout:   cfg.Timeout,
-		httpClient: &http.Client{
-			Timeout: time.Duration(cfg.Timeout) * time.Second,
-		},
+// Result 生成结果
+type Result struct {
+	Provider   string  `json:"provider"` // 实际生成结果的提供商
+	Model      string  `json:"model"`
+	Text       string  `json:"text"`
+	StopReason string  `json:"stopReason"` // 结束原因（end_turn、max_tokens、stop、length等）
+	Usage      Usage   `json:"usage"`
+	Cost       float64 `json:"cost"`             // 按提供商配置的价格计算的成本（美元）
+	Cached     bool    `json:"cached,omitempty"` // 结果来自缓存，未实际调用提供商
+}
+
+// ModelName 客户端使用的模型，组合客户端按优先级以逗号拼接；未实现Model方法的自定义驱动为空
+func ModelName(c Client) string {
+	if m, ok := c.(interface{ Model() string }); ok {
+		return m.Model()
 	}
+	return ""
 }
 
-// Generate 生成文本（非流式）
-func (c *client) Generate(ctx context.Context, prompt string, maxTokens int) (string, error) {
-	if maxTokens == 0 {
-		maxTokens = c.maxTokens
+// NewClient 按配置中的驱动类型创建AI客户端，name为注册到Registry中的提供商名称
+func NewClient(name string, cfg *config.AIProviderConfig) (Client, error) {
+	driverType := cfg.Type
+	if driverType == "" {
+		driverType = defaultDriverType(name)
 	}
 
-	switch c.provider {
-	case ProviderClaude:
-		return c.generateClaude(ctx, prompt, maxTokens)
-	case ProviderDeepSeek:
-		return c.generateDeepSeek(ctx, prompt, maxTokens)
-	default:
-		return "", fmt.Errorf("unsupported provider: %s", c.provider)
+	driver, ok := lookupDriver(driverType)
+	if !ok {
+		return nil, fmt.Errorf("unknown AI driver %q for provider %s", driverType, name)
 	}
+	return driver(name, cfg)
 }
 
-// GenerateStream 生成文本（流式）
-func (c *client) GenerateStream(ctx context.Context, prompt string, maxTokens int, callback func(string)) error {
-	// 流式生成暂时使用非流式实现
-	result, err := c.Generate(ctx, prompt, maxTokens)
-	if err != nil {
-		return err
+// defaultDriverType 未配置type时按提供商名称推断驱动类型
+func defaultDriverType(name string) string {
+	if name == string(ProviderClaude) {
+		return DriverAnthropic
 	}
-	callback(result)
-	return nil
+	return DriverOpenAI
 }
 
-// generateClaude 使用Claude生成文本
-func (c *client) generateClaude(ctx context.Context, prompt string, maxTokens int) (string, error) {
-	// Claude API请求结构
-	reqBody := map[string]interface{}{
-		"model": c.model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-		"max_tokens": maxTokens,
-	}
+// httpClient 基于HTTP接口的客户端公共部分
+type httpClient struct {
+	name      string
+	baseURL   string
+	apiKey    string
+	model     string
+	headers   map[string]string
+	maxTokens int
+	pricing   pricing
+	client    *http.Client
+}
+
+// pricing 提供商价格（美元/百万token）
+type pricing struct {
+	input  float64
+	output float64
+}
 
-	return c.makeRequest(ctx, "https://api.anthropic.com/v1/messages", reqBody, "anthropic")
+// cost 计算用量对应的成本
+func (p pricing) cost(usage Usage) float64 {
+	return (float64(usage.InputTokens)*p.input + float64(usage.OutputTokens)*p.output) / 1e6
 }
 
-// generateDeepSeek 使用DeepSeek生成文本
-func (c *client) generateDeepSeek(ctx context.Context, prompt string, maxTokens int) (string, error) {
-	// DeepSeek API请求结构（OpenAI兼容）
-	reqBody := map[string]interface{}{
-		"model": c.model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
+// newHTTPClient 根据配置构建HTTP客户端，baseURL为空时使用驱动的默认地址
+func newHTTPClient(name string, cfg *config.AIProviderConfig, defaultBaseURL string) httpClient {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return httpClient{
+		name:      name,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		apiKey:    cfg.APIKey,
+		model:     cfg.Model,
+		headers:   cfg.Headers,
+		maxTokens: cfg.MaxTokens,
+		pricing: pricing{
+			input:  cfg.InputPrice,
+			output: cfg.OutputPrice,
+		},
+		client: &http.Client{
+			Timeout: time.Duration(cfg.Timeout) * time.Second,
 		},
-		"max_tokens": maxTokens,
 	}
+}
+
+// Name 提供商名称
+func (c *httpClient) Name() string {
+	return c.name
+}
+
+// Model 配置的模型名称
+func (c *httpClient) Model() string {
+	return c.model
+}
 
-	return c.makeRequest(ctx, "https://api.deepseek.com/v1/chat/completions", reqBody, "openai")
+// tokens 未指定maxTokens时使用配置值
+func (c *httpClient) tokens(maxTokens int) int {
+	if maxTokens == 0 {
+		return c.maxTokens
+	}
+	return maxTokens
 }
 
-// makeRequest 发送HTTP请求
-func (c *client) makeRequest(ctx context.Context, url string, reqBody map[string]interface{}, apiType string) (string, error) {
+// post 发送JSON请求，非200响应转换为APIError；setAuth用于设置驱动特有的认证头
+func (c *httpClient) post(ctx context.Context, path string, reqBody interface{}, stream bool, setAuth func(*http.Request)) (*http.Response, error) {
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// 设置请求头
 	req.Header.Set("Content-Type", "application/json")
-	if apiType == "anthropic" {
-		req.Header.Set("x-api-key", c.apiKey)
-		req.Header.Set("anthropic-version", "2023-06-01")
-	} else {
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	if setAuth != nil {
+		setAuth(req)
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(c.name, resp, body)
 	}
 
-	// 发送请求
-	resp, err := c.httpClient.Do(req)
+	return resp, nil
+}
+
+// postJSON 发送非流式请求并解析响应
+func (c *httpClient) postJSON(ctx context.Context, path string, reqBody interface{}, setAuth func(*http.Request), out interface{}) error {
+	resp, err := c.post(ctx, path, reqBody, false, setAuth)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
-	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return fmt.Errorf("failed to read response: %w", err)
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	return nil
+}
 
-	// 解析响应
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+// newResult 构建带提供商和模型信息的结果
+func (c *httpClient) newResult() *Result {
+	return &Result{
+		Provider: c.name,
+		Model:    c.model,
 	}
+}
 
-	// 提取生成的文本
-	if apiType == "anthropic" {
-		// Claude响应格式
-		if content, ok := result["content"].([]interface{}); ok && len(content) > 0 {
-			if textBlock, ok := content[0].(map[string]interface{}); ok {
-				if text, ok := textBlock["text"].(string); ok {
-					return text, nil
-				}
-			}
-		}
-	} else {
-		// OpenAI兼容格式
-		if choices, ok := result["choices"].([]interface{}); ok && len(choices) > 0 {
-			if choice, ok := choices[0].(map[string]interface{}); ok {
-				if message, ok := choice["message"].(map[string]interface{}); ok {
-					if content, ok := message["content"].(string); ok {
-						return content, nil
-					}
-				}
-			}
-		}
-	}
+// finish 用量确定后计算成本
+func (c *httpClient) finish(result *Result) *Result {
+	result.Cost = c.pricing.cost(result.Usage)
+	return result
+}
 
-	return "", fmt.Errorf("failed to extract text from response")
+// userMessages 构建单条用户消息
+func userMessages(prompt string) []map[string]string {
+	return []map[string]string{
+		{"role": "user", "content": prompt},
+	}
 }
 
 // SelectProvider 根据任务类型选择AI提供商
 func SelectProvider(taskType string) Provider {
 	// 高质量任务使用Claude
 	highQualityTasks := map[string]bool{
-		"outline":           true,
-		"polish":            true,
-		"consistency_check": true,
-		"screenplay_format": true,
+		"outline":             true,
+		"polish":              true,
+		"consistency_check":   true,
+		"screenplay_format":   true,
+		"novel_to_screenplay": true,
+		"screenplay_to_novel": true,
+		"snowflake":           true,
 	}
 
 	if highQualityTasks[taskType] {
@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	MaxRetries     int           // 单个提供商的最大重试次数（不含首次请求）
+	InitialBackoff time.Duration // 首次重试前的等待时间，之后按指数增长
+	MaxBackoff     time.Duration // 单次等待的上限；服务商要求等待更久时直接切换到下一个提供商
+}
+
+// failoverClient 按顺序尝试多个提供商的组合客户端
+type failoverClient struct {
+	clients []Client
+	policy  RetryPolicy
+}
+
+// NewFailoverClient 创建组合客户端：对每个提供商按退避策略重试，失败后依次切换到下一个提供商
+func NewFailoverClient(clients []Client, policy RetryPolicy) Client {
+	if len(clients) == 1 && policy.MaxRetries <= 0 {
+		return clients[0]
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = time.Second
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 30 * time.Second
+	}
+	return &failoverClient{
+		clients: clients,
+		policy:  policy,
+	}
+}
+
+// Name 提供商名称（按优先级排列）
+func (f *failoverClient) Name() string {
+	names := make([]string, len(f.clients))
+	for i, c := range f.clients {
+		names[i] = c.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Model 各提供商的模型（按优先级排列）
+func (f *failoverClient) Model() string {
+	models := make([]string, len(f.clients))
+	for i, c := range f.clients {
+		models[i] = ModelName(c)
+	}
+	return strings.Join(models, ",")
+}
+
+// Generate 生成文本（非流式）
+func (f *failoverClient) Generate(ctx context.Context, prompt string, maxTokens int) (*Result, error) {
+	var result *Result
+	err := f.run(ctx, func(c Client) (bool, error) {
+		var err error
+		result, err = c.Generate(ctx, prompt, maxTokens)
+		return false, err
+	})
+	return result, err
+}
+
+// GenerateStream 生成文本（流式）
+//
+// 一旦已经向调用方输出了部分文本，就不再重试或切换提供商，避免输出内容重复。
+func (f *failoverClient) GenerateStream(ctx context.Context, prompt string, maxTokens int, callback func(string)) (*Result, error) {
+	var result *Result
+	err := f.run(ctx, func(c Client) (bool, error) {
+		started := false
+		var err error
+		result, err = c.GenerateStream(ctx, prompt, maxTokens, func(delta string) {
+			started = true
+			callback(delta)
+		})
+		return started, err
+	})
+	return result, err
+}
+
+// run 按提供商顺序和重试策略执行请求；attempt返回是否已产生输出以及错误
+func (f *failoverClient) run(ctx context.Context, attempt func(c Client) (bool, error)) error {
+	var errs []error
+	for _, c := range f.clients {
+		for retry := 0; ; retry++ {
+			started, err := attempt(c)
+			if err == nil {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if started {
+				return err
+			}
+
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name(), err))
+			if !IsRetryable(err) || retry >= f.policy.MaxRetries {
+				break
+			}
+
+			wait := retryAfter(err)
+			if wait == 0 {
+				wait = f.backoff(retry)
+			}
+			if wait > f.policy.MaxBackoff {
+				// 服务商要求等待过久，直接切换
+				break
+			}
+
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	return fmt.Errorf("all AI providers failed: %w", errors.Join(errs...))
+}
+
+// backoff 计算第retry次重试前的指数退避时间（带随机抖动）
+func (f *failoverClient) backoff(retry int) time.Duration {
+	d := f.policy.InitialBackoff << retry
+	if d <= 0 || d > f.policy.MaxBackoff {
+		d = f.policy.MaxBackoff
+	}
+	// 在[d/2, d]之间随机，避免多个任务同时重试
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,239 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jugo/backend/config"
+)
+
+// 提示词中的模拟指令，用于按需触发异常（配置的fail_every、rate_limit_every之外）
+const (
+	MockDirectiveFail        = "[mock:fail]"         // 返回500
+	MockDirectiveRateLimit   = "[mock:rate_limit]"   // 返回429
+	MockDirectiveStreamError = "[mock:stream_error]" // 流式输出第一段后中断
+)
+
+const (
+	defaultMockModel       = "mock"
+	defaultMockChunkChars  = 20
+	defaultMockOutputChars = 300
+)
+
+// mockSentences 生成模拟文本的句子
+var mockSentences = []string{
+	"夜色渐深，街角的灯一盏接一盏亮了起来。",
+	"他停下脚步，回头望了一眼来时的路。",
+	"风从窗缝里钻进来，吹乱了桌上的稿纸。",
+	"她没有说话，只是把手里的信又折了一折。",
+	"远处传来几声犬吠，很快又归于寂静。",
+	"雨点敲在屋檐上，像是有人在轻轻叩门。",
+	"他终于明白，那天的约定从来不是玩笑。",
+	"门外的脚步声越来越近，又在门前停住。",
+	"桌上的茶早已凉透，却没有人起身去换。",
+	"她笑了笑，转身走进了人群。",
+}
+
+// mockClient 不访问网络的模拟客户端，相同提示词总是得到相同的输出，用于本地开发和集成测试
+type mockClient struct {
+	name      string
+	model     string
+	maxTokens int
+	pricing   pricing
+	cfg       config.AIMockConfig
+	calls     atomic.Int64
+}
+
+// newMockClient 创建模拟客户端
+func newMockClient(name string, cfg *config.AIProviderConfig) (Client, error) {
+	model := cfg.Model
+	if model == "" {
+		model = defaultMockModel
+	}
+	return &mockClient{
+		name:      name,
+		model:     model,
+		maxTokens: cfg.MaxTokens,
+		pricing: pricing{
+			input:  cfg.InputPrice,
+			output: cfg.OutputPrice,
+		},
+		cfg: cfg.Mock,
+	}, nil
+}
+
+// Name 提供商名称
+func (c *mockClient) Name() string {
+	return c.name
+}
+
+// Model 配置的模型名称
+func (c *mockClient) Model() string {
+	return c.model
+}
+
+// Generate 生成文本（非流式）
+func (c *mockClient) Generate(ctx context.Context, prompt string, maxTokens int) (*Result, error) {
+	return c.generate(ctx, prompt, maxTokens, nil)
+}
+
+// GenerateStream 生成文本（流式，按chunk_chars分段输出）
+func (c *mockClient) GenerateStream(ctx context.Context, prompt string, maxTokens int, callback func(string)) (*Result, error) {
+	return c.generate(ctx, prompt, maxTokens, callback)
+}
+
+// generate 模拟一次请求：按配置和提示词指令返回错误，否则在延迟后输出确定的文本；callback为nil时不分段
+func (c *mockClient) generate(ctx context.Context, prompt string, maxTokens int, callback func(string)) (*Result, error) {
+	n := c.calls.Add(1)
+	if err := sleepContext(ctx, time.Duration(c.cfg.LatencyMs)*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if err := c.fault(prompt, n); err != nil {
+		return nil, err
+	}
+
+	if maxTokens == 0 {
+		maxTokens = c.maxTokens
+	}
+	text, stopReason := c.respond(prompt, maxTokens)
+
+	if callback != nil {
+		chunks := splitChunks(text, c.chunkChars())
+		for i, chunk := range chunks {
+			if i > 0 {
+				if err := sleepContext(ctx, time.Duration(c.cfg.ChunkDelayMs)*time.Millisecond); err != nil {
+					return nil, err
+				}
+			}
+			callback(chunk)
+			if strings.Contains(prompt, MockDirectiveStreamError) {
+				return nil, errors.New("failed to read stream: mock stream interrupted")
+			}
+		}
+	}
+
+	result := &Result{
+		Provider:   c.name,
+		Model:      c.model,
+		Text:       text,
+		StopReason: stopReason,
+		Usage: Usage{
+			InputTokens:  utf8.RuneCountInString(prompt),
+			OutputTokens: utf8.RuneCountInString(text),
+		},
+	}
+	result.Cost = c.pricing.cost(result.Usage)
+	return result, nil
+}
+
+// fault 第n次请求需要模拟的错误
+func (c *mockClient) fault(prompt string, n int64) error {
+	switch {
+	case strings.Contains(prompt, MockDirectiveRateLimit),
+		c.cfg.RateLimitEvery > 0 && n%int64(c.cfg.RateLimitEvery) == 0:
+		return &APIError{
+			Provider:   c.name,
+			StatusCode: http.StatusTooManyRequests,
+			Body:       `{"error":"mock rate limit"}`,
+			RetryAfter: time.Duration(c.cfg.RetryAfterMs) * time.Millisecond,
+		}
+	case strings.Contains(prompt, MockDirectiveFail),
+		c.cfg.FailEvery > 0 && n%int64(c.cfg.FailEvery) == 0:
+		return &APIError{
+			Provider:   c.name,
+			StatusCode: http.StatusInternalServerError,
+			Body:       `{"error":"mock failure"}`,
+		}
+	}
+	return nil
+}
+
+// respond 生成输出：提示词匹配配置的responses时返回对应内容，否则以提示词哈希为种子拼接句子；
+// 超出maxTokens（按一字一token计）时截断
+func (c *mockClient) respond(prompt string, maxTokens int) (string, string) {
+	text, ok := c.scripted(prompt)
+	if !ok {
+		seed := fnv.New64a()
+		seed.Write([]byte(prompt))
+		rng := rand.New(rand.NewSource(int64(seed.Sum64())))
+
+		outputChars := c.cfg.OutputChars
+		if outputChars <= 0 {
+			outputChars = defaultMockOutputChars
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "【模拟输出 %016x】", seed.Sum64())
+		for utf8.RuneCountInString(b.String()) < outputChars {
+			b.WriteString(mockSentences[rng.Intn(len(mockSentences))])
+		}
+		text = b.String()
+	}
+
+	if maxTokens > 0 && utf8.RuneCountInString(text) > maxTokens {
+		return string([]rune(text)[:maxTokens]), "max_tokens"
+	}
+	return text, "end_turn"
+}
+
+// scripted 按key排序查找提示词包含的第一个key（不区分大小写，配置文件中的key会被转为小写）
+func (c *mockClient) scripted(prompt string) (string, bool) {
+	if len(c.cfg.Responses) == 0 {
+		return "", false
+	}
+	keys := make([]string, 0, len(c.cfg.Responses))
+	for key := range c.cfg.Responses {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lower := strings.ToLower(prompt)
+	for _, key := range keys {
+		if strings.Contains(lower, strings.ToLower(key)) {
+			return c.cfg.Responses[key], true
+		}
+	}
+	return "", false
+}
+
+// chunkChars 流式输出每段的字数
+func (c *mockClient) chunkChars() int {
+	if c.cfg.ChunkChars <= 0 {
+		return defaultMockChunkChars
+	}
+	return c.cfg.ChunkChars
+}
+
+// splitChunks 按字数切分文本
+func splitChunks(text string, size int) []string {
+	runes := []rune(text)
+	var chunks []string
+	for start := 0; start < len(runes); start += size {
+		end := min(start+size, len(runes))
+		chunks = append(chunks, string(runes[start:end]))
+	}
+	return chunks
+}
+
+// sleepContext 等待d，ctx取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
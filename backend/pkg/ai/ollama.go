@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jugo/backend/config"
+)
+
+const ollamaBaseURL = "http://localhost:11434"
+
+// ollamaClient 本地Ollama客户端（/api/chat接口）
+type ollamaClient struct {
+	httpClient
+}
+
+// newOllamaClient 创建Ollama客户端
+func newOllamaClient(name string, cfg *config.AIProviderConfig) (Client, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("model is required for ollama provider %s", name)
+	}
+	return &ollamaClient{httpClient: newHTTPClient(name, cfg, ollamaBaseURL)}, nil
+}
+
+// ollamaResponse Ollama响应结构，流式时每行一个
+type ollamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+// request 构建请求体，Ollama通过options.num_predict限制输出长度
+func (c *ollamaClient) request(prompt string, maxTokens int, stream bool) map[string]interface{} {
+	reqBody := map[string]interface{}{
+		"model":    c.model,
+		"messages": userMessages(prompt),
+		"stream":   stream,
+	}
+	if n := c.tokens(maxTokens); n > 0 {
+		reqBody["options"] = map[string]int{"num_predict": n}
+	}
+	return reqBody
+}
+
+// Generate 生成文本（非流式）
+func (c *ollamaClient) Generate(ctx context.Context, prompt string, maxTokens int) (*Result, error) {
+	var resp ollamaResponse
+	if err := c.postJSON(ctx, "/api/chat", c.request(prompt, maxTokens, false), nil, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("API error: %s", resp.Error)
+	}
+
+	result := c.newResult()
+	result.Text = resp.Message.Content
+	result.StopReason = resp.DoneReason
+	result.Usage = Usage{InputTokens: resp.PromptEvalCount, OutputTokens: resp.EvalCount}
+	return c.finish(result), nil
+}
+
+// GenerateStream 生成文本（流式，响应为逐行JSON）
+func (c *ollamaClient) GenerateStream(ctx context.Context, prompt string, maxTokens int, callback func(string)) (*Result, error) {
+	resp, err := c.post(ctx, "/api/chat", c.request(prompt, maxTokens, true), false, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := c.newResult()
+	var text strings.Builder
+	done := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("API stream error: %s", chunk.Error)
+		}
+
+		if chunk.Message.Content != "" {
+			text.WriteString(chunk.Message.Content)
+			callback(chunk.Message.Content)
+		}
+		if chunk.Done {
+			done = true
+			result.StopReason = chunk.DoneReason
+			result.Usage.InputTokens = chunk.PromptEvalCount
+			result.Usage.OutputTokens = chunk.EvalCount
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	// 没有收到done的响应说明连接被提前关闭
+	if !done {
+		return nil, fmt.Errorf("failed to read stream: %w", io.ErrUnexpectedEOF)
+	}
+
+	result.Text = text.String()
+	return c.finish(result), nil
+}
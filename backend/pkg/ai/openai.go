@@ -0,0 +1,154 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jugo/backend/config"
+)
+
+const (
+	openAIBaseURL   = "https://api.openai.com/v1"
+	deepSeekBaseURL = "https://api.deepseek.com/v1"
+)
+
+// openAIClient OpenAI兼容接口客户端（DeepSeek、vLLM、各类网关等）
+type openAIClient struct {
+	httpClient
+}
+
+// newOpenAIClient 创建OpenAI兼容客户端，base_url需包含版本前缀（如 http://host:8000/v1）
+func newOpenAIClient(name string, cfg *config.AIProviderConfig) (Client, error) {
+	defaultBaseURL := openAIBaseURL
+	if name == string(ProviderDeepSeek) {
+		defaultBaseURL = deepSeekBaseURL
+	}
+	return &openAIClient{httpClient: newHTTPClient(name, cfg, defaultBaseURL)}, nil
+}
+
+// setAuth 设置认证头，未配置api_key时不发送（本地部署的服务通常无需认证）
+func (c *openAIClient) setAuth(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
+
+// Generate 生成文本（非流式）
+func (c *openAIClient) Generate(ctx context.Context, prompt string, maxTokens int) (*Result, error) {
+	reqBody := map[string]interface{}{
+		"model":      c.model,
+		"messages":   userMessages(prompt),
+		"max_tokens": c.tokens(maxTokens),
+	}
+
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := c.postJSON(ctx, "/chat/completions", reqBody, c.setAuth, &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("failed to extract text from response")
+	}
+	result := c.newResult()
+	result.Text = resp.Choices[0].Message.Content
+	result.StopReason = resp.Choices[0].FinishReason
+	result.Usage = Usage{InputTokens: resp.Usage.PromptTokens, OutputTokens: resp.Usage.CompletionTokens}
+	return c.finish(result), nil
+}
+
+// GenerateStream 生成文本（流式）
+func (c *openAIClient) GenerateStream(ctx context.Context, prompt string, maxTokens int, callback func(string)) (*Result, error) {
+	reqBody := map[string]interface{}{
+		"model":      c.model,
+		"messages":   userMessages(prompt),
+		"max_tokens": c.tokens(maxTokens),
+		"stream":     true,
+		"stream_options": map[string]bool{
+			"include_usage": true,
+		},
+	}
+
+	resp, err := c.post(ctx, "/chat/completions", reqBody, true, c.setAuth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := c.newResult()
+	if err := readOpenAIStream(resp.Body, result, callback); err != nil {
+		return nil, err
+	}
+	return c.finish(result), nil
+}
+
+// readOpenAIStream 解析OpenAI兼容格式的流式响应
+func readOpenAIStream(r io.Reader, result *Result, callback func(string)) error {
+	var text strings.Builder
+	err := readSSE(r, func(ev *sseEvent) (bool, error) {
+		if ev.Data == "[DONE]" {
+			return false, nil
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+				FinishReason *string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *struct {
+				PromptTokens     int `json:"prompt_tokens"`
+				CompletionTokens int `json:"completion_tokens"`
+			} `json:"usage"`
+			Error *struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			return false, fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+
+		if chunk.Error != nil {
+			return false, fmt.Errorf("API stream error (%s): %s", chunk.Error.Type, chunk.Error.Message)
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				text.WriteString(choice.Delta.Content)
+				callback(choice.Delta.Content)
+			}
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				result.StopReason = *choice.FinishReason
+			}
+		}
+
+		// 开启include_usage后，最后一个chunk携带用量信息
+		if chunk.Usage != nil {
+			result.Usage.InputTokens = chunk.Usage.PromptTokens
+			result.Usage.OutputTokens = chunk.Usage.CompletionTokens
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	result.Text = text.String()
+	return nil
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jugo/backend/config"
+)
+
+// 内置驱动类型
+const (
+	DriverAnthropic = "anthropic"
+	DriverOpenAI    = "openai"
+	DriverOllama    = "ollama"
+	DriverMock      = "mock"
+)
+
+// Driver 根据提供商名称和配置创建客户端
+type Driver func(name string, cfg *config.AIProviderConfig) (Client, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{
+		DriverAnthropic: newAnthropicClient,
+		DriverOpenAI:    newOpenAIClient,
+		DriverOllama:    newOllamaClient,
+		DriverMock:      newMockClient,
+	}
+)
+
+// RegisterDriver 注册驱动类型，配置中的type字段引用该名称；重复注册会覆盖已有驱动
+func RegisterDriver(driverType string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[driverType] = driver
+}
+
+// lookupDriver 查找驱动
+func lookupDriver(driverType string) (Driver, bool) {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	driver, ok := drivers[driverType]
+	return driver, ok
+}
+
+// Registry 按名称管理的提供商客户端
+type Registry struct {
+	mu      sync.RWMutex
+	clients map[string]Client
+}
+
+// NewRegistry 创建空的提供商注册表
+func NewRegistry() *Registry {
+	return &Registry{clients: make(map[string]Client)}
+}
+
+// NewRegistryFromConfig 根据配置创建注册表：先注册claude、deepseek，再注册providers中的提供商（同名覆盖）
+func NewRegistryFromConfig(cfg *config.AIConfig) (*Registry, error) {
+	providers := map[string]config.AIProviderConfig{
+		string(ProviderClaude):   cfg.Claude,
+		string(ProviderDeepSeek): cfg.DeepSeek,
+	}
+	for name, providerCfg := range cfg.Providers {
+		providers[name] = providerCfg
+	}
+
+	registry := NewRegistry()
+	for name, providerCfg := range providers {
+		client, err := NewClient(name, &providerCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AI provider %s: %w", name, err)
+		}
+		registry.Register(client)
+	}
+	return registry, nil
+}
+
+// Register 以客户端名称注册，已存在时覆盖（可用于测试时替换为桩实现）
+func (r *Registry) Register(client Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[client.Name()] = client
+}
+
+// Get 按名称获取客户端
+func (r *Registry) Get(name string) (Client, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	client, ok := r.clients[name]
+	return client, ok
+}
+
+// Names 已注册的提供商名称（按字母排序）
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.clients))
+	for name := range r.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
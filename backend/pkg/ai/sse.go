@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxSSELineSize 单行SSE数据的最大长度
+const maxSSELineSize = 1024 * 1024
+
+// sseEvent SSE事件
+type sseEvent struct {
+	Event string
+	Data  string
+}
+
+// readSSE 逐个读取SSE事件并交给handler处理，handler在收到结束事件（如message_stop、[DONE]）时返回false停止读取；
+// 没有收到结束事件流就结束时返回io.ErrUnexpectedEOF，避免把被截断的输出当作完整结果
+func readSSE(r io.Reader, handler func(ev *sseEvent) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineSize)
+
+	ev := &sseEvent{}
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// 空行表示一个事件结束
+		if line == "" {
+			if len(data) == 0 {
+				ev = &sseEvent{}
+				continue
+			}
+			ev.Data = strings.Join(data, "\n")
+			cont, err := handler(ev)
+			if err != nil {
+				return err
+			}
+			if !cont {
+				return nil
+			}
+			ev = &sseEvent{}
+			data = data[:0]
+			continue
+		}
+
+		// 注释行
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	// 处理末尾没有空行结束的事件
+	if len(data) > 0 {
+		ev.Data = strings.Join(data, "\n")
+		cont, err := handler(ev)
+		if err != nil {
+			return err
+		}
+		if !cont {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to read stream: %w", io.ErrUnexpectedEOF)
+}
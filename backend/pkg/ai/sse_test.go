@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadSSE(t *testing.T) {
+	errStop := errors.New("stop")
+	tests := []struct {
+		name    string
+		stream  string
+		want    []sseEvent
+		wantErr error
+	}{
+		{
+			name:   "events until terminal event",
+			stream: "event: delta\ndata: a\n\n: keep-alive\n\ndata: b\ndata: c\n\nevent: stop\ndata: end\n\ndata: ignored\n\n",
+			want: []sseEvent{
+				{Event: "delta", Data: "a"},
+				{Data: "b\nc"},
+				{Event: "stop", Data: "end"},
+			},
+		},
+		{
+			name:   "terminal event without trailing blank line",
+			stream: "data: a\n\nevent: stop\ndata: end",
+			want: []sseEvent{
+				{Data: "a"},
+				{Event: "stop", Data: "end"},
+			},
+		},
+		{
+			name:    "stream ends before terminal event",
+			stream:  "data: a\n\ndata: b\n\n",
+			want:    []sseEvent{{Data: "a"}, {Data: "b"}},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "truncated event",
+			stream:  "data: a\n\ndata: b",
+			want:    []sseEvent{{Data: "a"}, {Data: "b"}},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "empty stream",
+			stream:  "",
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "handler error",
+			stream:  "data: a\n\nevent: fail\ndata: x\n\n",
+			want:    []sseEvent{{Data: "a"}, {Event: "fail", Data: "x"}},
+			wantErr: errStop,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []sseEvent
+			err := readSSE(strings.NewReader(tt.stream), func(ev *sseEvent) (bool, error) {
+				got = append(got, *ev)
+				switch ev.Event {
+				case "stop":
+					return false, nil
+				case "fail":
+					return false, errStop
+				}
+				return true, nil
+			})
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("readSSE() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readSSE() events = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadOpenAIStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		stream   string
+		wantText string
+		wantStop string
+		wantErr  error
+	}{
+		{
+			name: "complete stream",
+			stream: `data: {"choices":[{"delta":{"content":"你好"}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{"content":"世界"},"finish_reason":"stop"}]}` + "\n\n" +
+				`data: {"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":4}}` + "\n\n" +
+				"data: [DONE]\n\n",
+			wantText: "你好世界",
+			wantStop: "stop",
+		},
+		{
+			name:     "connection closed before [DONE]",
+			stream:   `data: {"choices":[{"delta":{"content":"你好"}}]}` + "\n\n",
+			wantText: "你好",
+			wantErr:  io.ErrUnexpectedEOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var text strings.Builder
+			result := &Result{}
+			err := readOpenAIStream(strings.NewReader(tt.stream), result, func(delta string) {
+				text.WriteString(delta)
+			})
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("readOpenAIStream() error = %v, want %v", err, tt.wantErr)
+			}
+			if text.String() != tt.wantText {
+				t.Errorf("streamed text = %q, want %q", text.String(), tt.wantText)
+			}
+			if err == nil && (result.Text != tt.wantText || result.StopReason != tt.wantStop) {
+				t.Errorf("result = %+v, want text %q and stop reason %q", result, tt.wantText, tt.wantStop)
+			}
+		})
+	}
+}
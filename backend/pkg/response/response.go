@@ -68,6 +68,11 @@ func NotFound(c *gin.Context, message string) {
 	Error(c, 404, message)
 }
 
+// TooManyRequests 429错误
+func TooManyRequests(c *gin.Context, message string, data interface{}) {
+	ErrorWithData(c, 429, message, data)
+}
+
 // InternalServerError 500错误
 func InternalServerError(c *gin.Context, message string) {
 	Error(c, 500, message)